@@ -0,0 +1,282 @@
+package deer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type CompressEncoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+type CompressOptions struct {
+	Level     int
+	MinLength int
+	SkipTypes []string
+	// Encoders adds or replaces encodings by Content-Encoding token. Only
+	// gzip and deflate are built in; brotli ("br") must be registered here.
+	Encoders map[string]func() CompressEncoder
+}
+
+var defaultCompressSkipTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/octet-stream",
+}
+
+// Compress encodes responses with the client's preferred encoding. The
+// built-in encodings are gzip and deflate (zlib-wrapped, per RFC 9110).
+func Compress(options ...CompressOptions) Middleware {
+	var finalOptions CompressOptions
+	if len(options) > 0 {
+		finalOptions = options[0]
+	}
+	if finalOptions.Level == 0 {
+		finalOptions.Level = gzip.DefaultCompression
+	}
+	if finalOptions.MinLength <= 0 {
+		finalOptions.MinLength = 1024
+	}
+	if finalOptions.SkipTypes == nil {
+		finalOptions.SkipTypes = defaultCompressSkipTypes
+	}
+	level := finalOptions.Level
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(newError("compress", "invalid level %d", level))
+	}
+	factories := map[string]func() CompressEncoder{
+		"gzip": func() CompressEncoder {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		},
+		"deflate": func() CompressEncoder {
+			w, _ := zlib.NewWriterLevel(io.Discard, level)
+			return w
+		},
+	}
+	var preferred []string
+	for name, factory := range finalOptions.Encoders {
+		name = strings.ToLower(name)
+		factories[name] = factory
+		if name != "gzip" && name != "deflate" {
+			preferred = append(preferred, name)
+		}
+	}
+	sort.Strings(preferred)
+	preferred = append(preferred, "gzip", "deflate")
+	pools := map[string]*sync.Pool{}
+	for name, factory := range factories {
+		factory := factory
+		pools[name] = &sync.Pool{New: func() interface{} { return factory() }}
+	}
+	return func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header("Accept-Encoding"), preferred)
+			if encoding == "" || r.HeaderExists("Upgrade") {
+				h.Next(w, r)
+				return
+			}
+			cw := &compressWriter{
+				raw:      w,
+				encoding: encoding,
+				pool:     pools[encoding],
+				options:  &finalOptions,
+			}
+			defer cw.close()
+			h.Next(WrapResponseWriter(cw), r)
+		}
+	}
+}
+
+func negotiateEncoding(header string, preferred []string) string {
+	if header == "" {
+		return ""
+	}
+	qs := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			name = strings.TrimSpace(part[:i])
+			params := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(params, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qs[strings.ToLower(name)] = q
+	}
+	best, bestQ := "", 0.0
+	for _, name := range preferred {
+		q, ok := qs[name]
+		if !ok {
+			q, ok = qs["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	raw        http.ResponseWriter
+	encoding   string
+	pool       *sync.Pool
+	options    *CompressOptions
+	encoder    CompressEncoder
+	buf        []byte
+	statusCode int
+	decided    bool
+}
+
+func (w *compressWriter) Header() http.Header {
+	return w.raw.Header()
+}
+
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.statusCode != 0 {
+		return
+	}
+	w.statusCode = statusCode
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.options.MinLength {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.raw.Write(b)
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.raw
+}
+
+func (w *compressWriter) features() (bool, bool, bool) {
+	return writerFeatures(w.raw)
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(len(w.buf) > 0); err != nil {
+			return
+		}
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.raw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.raw.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.raw.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.raw.Header()
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && w.compressible() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.encoder = w.pool.Get().(CompressEncoder)
+		w.encoder.Reset(w.raw)
+	}
+	w.raw.WriteHeader(w.statusCode)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.raw.Write(buf)
+	}
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	header := w.raw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if w.statusCode < http.StatusOK || w.statusCode == http.StatusNoContent || w.statusCode == http.StatusNotModified {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range w.options.SkipTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.statusCode == 0 {
+			return
+		}
+		_ = w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,71 @@
+package deer
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	long := strings.Repeat("deer ", 400)
+	router := NewRouter().Use(Compress(CompressOptions{MinLength: 100}))
+	router.Get("/long", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusOK, long)
+	})
+	router.Get("/short", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusOK, "short")
+	})
+	router.Get("/image", func(w ResponseWriter, r *Request) {
+		w.SetHeader("Content-Type", "image/png")
+		w.Write([]byte(long))
+	})
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		"gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"deflate": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	}
+	tests := []struct {
+		path     string
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"/long", "gzip", "gzip", long},
+		{"/long", "deflate", "deflate", long},
+		{"/long", "gzip;q=0.5, deflate", "deflate", long},
+		{"/long", "br", "", long},
+		{"/long", "gzip;q=0", "", long},
+		{"/long", "", "", long},
+		{"/short", "gzip", "", "short"},
+		{"/image", "gzip", "", long},
+	}
+	for _, tt := range tests {
+		rec := serve(router, http.MethodGet, tt.path, "Accept-Encoding", tt.accept)
+		if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("GET %s accept %q: got encoding %q, want %q", tt.path, tt.accept, got, tt.encoding)
+			continue
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("GET %s accept %q: got vary %q", tt.path, tt.accept, rec.Header().Get("Vary"))
+		}
+		var body io.Reader = rec.Body
+		if tt.encoding != "" {
+			r, err := readers[tt.encoding](rec.Body)
+			if err != nil {
+				t.Errorf("GET %s accept %q: %v", tt.path, tt.accept, err)
+				continue
+			}
+			body = r
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			t.Errorf("GET %s accept %q: %v", tt.path, tt.accept, err)
+			continue
+		}
+		if string(b) != tt.body {
+			t.Errorf("GET %s accept %q: got body %q", tt.path, tt.accept, b)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package deer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type constraint struct {
+	spec  string
+	parse func(s string) (interface{}, bool)
+}
+
+func parseConstraint(spec string) (*constraint, error) {
+	switch {
+	case spec == "int":
+		return &constraint{spec: spec, parse: func(s string) (interface{}, bool) {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, false
+			}
+			return i, true
+		}}, nil
+	case spec == "uuid":
+		return &constraint{spec: spec, parse: func(s string) (interface{}, bool) {
+			u, err := ParseUUID(s)
+			if err != nil {
+				return nil, false
+			}
+			return u, true
+		}}, nil
+	case strings.HasPrefix(spec, "regex:"):
+		re, err := regexp.Compile("^(?:" + strings.TrimPrefix(spec, "regex:") + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return &constraint{spec: spec, parse: func(s string) (interface{}, bool) {
+			return nil, re.MatchString(s)
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown constraint %q", spec)
+	}
+}
+
+func splitParam(s string, sep byte) (name string, spec string, rest string, err error) {
+	i := 0
+	for i < len(s) && s[i] != sep && s[i] != '<' {
+		i++
+	}
+	name = s[:i]
+	if i == len(s) || s[i] != '<' {
+		return name, "", s[i:], nil
+	}
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				spec, rest = s[i+1:j], s[j+1:]
+				if rest != "" && rest[0] != sep {
+					return "", "", "", fmt.Errorf("constraint of param %q must end the segment", name)
+				}
+				return name, spec, rest, nil
+			}
+		}
+	}
+	return "", "", "", fmt.Errorf("unclosed constraint of param %q", name)
+}
+
+type UUID [16]byte
+
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, newError("uuid", "invalid uuid %q", s)
+	}
+	b, err := hex.DecodeString(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+	if err != nil {
+		return u, newError("uuid", "invalid uuid %q", s)
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,63 @@
+package deer
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestConstraintRouting(t *testing.T) {
+	router := NewRouter()
+	router.Get("/items/:id<int>", func(w ResponseWriter, r *Request) {
+		id, err := r.ParamInt("id")
+		if err != nil {
+			t.Errorf("param int: %v", err)
+		}
+		w.JSON(http.StatusOK, id)
+	})
+	router.Get("/items/:id<uuid>", func(w ResponseWriter, r *Request) {
+		id, err := r.ParamUUID("id")
+		if err != nil {
+			t.Errorf("param uuid: %v", err)
+		}
+		w.Text(http.StatusOK, "uuid "+id.String())
+	})
+	router.Get("/tags/:tag<regex:[a-z]+(-[a-z]+)*>", echoRoute)
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/items/42", http.StatusOK, "42\n"},
+		{"/items/6ba7b810-9dad-11d1-80b4-00c04fd430c8", http.StatusOK, "uuid 6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"/items/abc", http.StatusNotFound, "Not Found"},
+		{"/tags/go-lang", http.StatusOK, "/tags/:tag<regex:[a-z]+(-[a-z]+)*>"},
+		{"/tags/Go", http.StatusNotFound, "Not Found"},
+	}
+	for _, tt := range tests {
+		rec := serve(router, http.MethodGet, tt.path)
+		if rec.Code != tt.code || rec.Body.String() != tt.body {
+			t.Errorf("GET %s: got %d %q, want %d %q", tt.path, rec.Code, rec.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestConstraintInvalid(t *testing.T) {
+	for _, pattern := range []string{"/a/:id<float>", "/a/:id<regex:[>", "/a/:id<int"} {
+		if err := NewRouter().TryHandle(http.MethodGet, pattern, echoRoute); err == nil {
+			t.Errorf("handle %q: want error", pattern)
+		}
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	const s = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	u, err := ParseUUID(s)
+	if err != nil || u.String() != s {
+		t.Errorf("parse %q: got %v %v", s, u, err)
+	}
+	for _, bad := range []string{"", "6ba7b810-9dad-11d1-80b4", "6ba7b810x9dad-11d1-80b4-00c04fd430c8", "zba7b810-9dad-11d1-80b4-00c04fd430c8"} {
+		if _, err := ParseUUID(bad); err == nil {
+			t.Errorf("parse %q: want error", bad)
+		}
+	}
+}
@@ -19,8 +19,10 @@ func Output(writer io.Writer) {
 	debugWriter = writer
 }
 
+// Default returns a router that logs every request, including the 500 that
+// Recovery writes for a panicking handler.
 func Default() *Router {
-	return NewRouter().Use(Recovery(), Trace())
+	return NewRouter().Use(AccessLog(), Recovery())
 }
 
 func debugf(format string, args ...interface{}) {
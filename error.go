@@ -0,0 +1,164 @@
+package deer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+type HTTPError struct {
+	Status  int         `json:"status"`
+	Code    string      `json:"code,omitempty"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	Err     error       `json:"-"`
+}
+
+func NewHTTPError(status int, code string, message string, details ...interface{}) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	e := &HTTPError{Status: status, Code: code, Message: message}
+	if len(details) > 0 {
+		e.Details = details[0]
+	}
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.Status, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HTTPError) WithErr(err error) *HTTPError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+func NewProblem(status int, detail ...string) *Problem {
+	p := &Problem{Status: status, Title: http.StatusText(status)}
+	if len(detail) > 0 {
+		p.Detail = detail[0]
+	}
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+	}
+	return fmt.Sprintf("%d %s", p.Status, p.Title)
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	if p.Type == "" {
+		m["type"] = "about:blank"
+	}
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+func (e *HTTPError) Problem() *Problem {
+	p := NewProblem(e.Status)
+	if e.Message != p.Title {
+		p.Detail = e.Message
+	}
+	if e.Code != "" || e.Details != nil {
+		p.Extensions = map[string]interface{}{}
+		if e.Code != "" {
+			p.Extensions["code"] = e.Code
+		}
+		if e.Details != nil {
+			p.Extensions["details"] = e.Details
+		}
+	}
+	return p
+}
+
+func handleError(w ResponseWriter, r *Request, err error) {
+	f := r.errorHandler
+	if f == nil {
+		f = defaultErrorHandleFunc
+	}
+	f(w, r, err)
+}
+
+func defaultErrorHandleFunc(w ResponseWriter, r *Request, err error) {
+	if w.Written() {
+		debugf("deer: error after response written: %v", err)
+		return
+	}
+	var p *Problem
+	if errors.As(err, &p) {
+		w.Problem(p)
+		return
+	}
+	var he *HTTPError
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		he = NewHTTPError(http.StatusUnprocessableEntity, "validation_failed", "", ve)
+	} else if !errors.As(err, &he) {
+		debugf("deer: error: %v", err)
+		he = NewHTTPError(http.StatusInternalServerError, "", "")
+	}
+	if r.problemDetails {
+		w.Problem(he.Problem())
+		return
+	}
+	if acceptsJSON(r) {
+		w.JSON(he.Status, he)
+		return
+	}
+	w.Text(he.Status, he.Message)
+}
+
+func panicError(v interface{}) error {
+	if err, ok := v.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", v)
+}
+
+func acceptsJSON(r *Request) bool {
+	for _, part := range strings.Split(r.Header("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package deer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandlers(t *testing.T) {
+	type payload struct {
+		Qty int `json:"qty"`
+	}
+	router := NewRouter()
+	router.PostE("/json", func(w ResponseWriter, r *Request) error {
+		var p payload
+		return r.BindJSON(&p)
+	})
+	router.PostE("/xml", func(w ResponseWriter, r *Request) error {
+		var p payload
+		return r.BindXML(&p)
+	})
+	router.GetE("/query", func(w ResponseWriter, r *Request) error {
+		var p payload
+		return r.BindQuery(&p)
+	})
+	router.PostE("/max", func(w ResponseWriter, r *Request) error {
+		var p payload
+		r.Raw.Body = http.MaxBytesReader(w, r.Raw.Body, 2)
+		return r.BindJSON(&p)
+	})
+	router.GetE("/items/:id", func(w ResponseWriter, r *Request) error {
+		_, err := r.ParamInt("id")
+		return err
+	})
+	router.GetE("/storage", func(w ResponseWriter, r *Request) error {
+		return fmt.Errorf("read snapshot: %w", io.EOF)
+	})
+	router.GetE("/atoi", func(w ResponseWriter, r *Request) error {
+		_, err := strconv.Atoi("stored value")
+		return err
+	})
+	router.GetE("/http", func(w ResponseWriter, r *Request) error {
+		return fmt.Errorf("wrapped: %w", NewHTTPError(http.StatusTeapot, "teapot", "short and stout"))
+	})
+	router.GetE("/internal", func(w ResponseWriter, r *Request) error {
+		return errors.New("database down")
+	})
+	router.GetE("/ok", func(w ResponseWriter, r *Request) error {
+		w.Text(http.StatusOK, "ok")
+		return nil
+	})
+	tests := []struct {
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, "/json", `{"qty":1}`, http.StatusOK},
+		{http.MethodPost, "/json", ``, http.StatusBadRequest},
+		{http.MethodPost, "/json", `{"qty":`, http.StatusBadRequest},
+		{http.MethodPost, "/json", `{"qty":}`, http.StatusBadRequest},
+		{http.MethodPost, "/json", `{"qty":"1"}`, http.StatusBadRequest},
+		{http.MethodPost, "/xml", `<payload><Qty>`, http.StatusBadRequest},
+		{http.MethodPost, "/xml", `<payload><Qty>x</Qty></payload>`, http.StatusBadRequest},
+		{http.MethodGet, "/query?qty=abc", ``, http.StatusBadRequest},
+		{http.MethodPost, "/max", `{"qty":1}`, http.StatusRequestEntityTooLarge},
+		{http.MethodGet, "/items/1", ``, http.StatusOK},
+		{http.MethodGet, "/items/abc", ``, http.StatusBadRequest},
+		{http.MethodGet, "/storage", ``, http.StatusInternalServerError},
+		{http.MethodGet, "/atoi", ``, http.StatusInternalServerError},
+		{http.MethodGet, "/http", ``, http.StatusTeapot},
+		{http.MethodGet, "/internal", ``, http.StatusInternalServerError},
+		{http.MethodGet, "/ok", ``, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s %q: got %d %q, want %d", tt.method, tt.target, tt.body, rec.Code, rec.Body.String(), tt.code)
+		}
+	}
+}
+
+func TestErrorHandlerJSON(t *testing.T) {
+	router := NewRouter()
+	router.GetE("/", func(w ResponseWriter, r *Request) error {
+		return NewHTTPError(http.StatusConflict, "duplicate", "", map[string]string{"id": "1"})
+	})
+	rec := serve(router, http.MethodGet, "/", "Accept", "application/json")
+	var he HTTPError
+	if err := json.Unmarshal(rec.Body.Bytes(), &he); err != nil {
+		t.Fatalf("%v: %q", err, rec.Body.String())
+	}
+	if rec.Code != http.StatusConflict || he.Status != http.StatusConflict || he.Code != "duplicate" || he.Message != "Conflict" {
+		t.Errorf("got %d %+v", rec.Code, he)
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	router := NewRouter().HandleError(func(w ResponseWriter, r *Request, err error) {
+		w.Text(http.StatusBadGateway, "custom: "+err.Error())
+	})
+	g := router.Group("/api")
+	g.GetE("/fail", func(w ResponseWriter, r *Request) error {
+		return errors.New("boom")
+	})
+	if rec := serve(router, http.MethodGet, "/api/fail"); rec.Code != http.StatusBadGateway || rec.Body.String() != "custom: boom" {
+		t.Errorf("got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	tests := []struct {
+		problem *Problem
+		want    string
+	}{
+		{NewProblem(http.StatusNotFound), `{"status":404,"title":"Not Found","type":"about:blank"}`},
+		{
+			&Problem{Type: "https://example.com/out-of-credit", Title: "Out of credit", Status: 403, Detail: "balance is 30", Instance: "/accounts/1", Extensions: map[string]interface{}{"balance": 30, "type": "ignored"}},
+			`{"balance":30,"detail":"balance is 30","instance":"/accounts/1","status":403,"title":"Out of credit","type":"https://example.com/out-of-credit"}`,
+		},
+		{
+			NewHTTPError(http.StatusConflict, "duplicate", "already exists", []string{"id"}).Problem(),
+			`{"code":"duplicate","detail":"already exists","details":["id"],"status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{NewHTTPError(http.StatusBadRequest, "", "").Problem(), `{"status":400,"title":"Bad Request","type":"about:blank"}`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.problem)
+		if err != nil {
+			t.Errorf("marshal %+v: %v", tt.problem, err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("marshal %+v: got %s, want %s", tt.problem, b, tt.want)
+		}
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	type form struct {
+		Name string `json:"name" validate:"required"`
+	}
+	router := NewRouter().ProblemDetails(true)
+	router.GetE("/error", func(w ResponseWriter, r *Request) error {
+		return errors.New("boom")
+	})
+	router.GetE("/problem", func(w ResponseWriter, r *Request) error {
+		return &Problem{Status: http.StatusPaymentRequired, Title: "Out of credit"}
+	})
+	router.PostE("/validate", func(w ResponseWriter, r *Request) error {
+		var f form
+		return r.BindJSON(&f)
+	})
+	router.Get("/auth", func(w ResponseWriter, r *Request) {}, BasicAuth(map[string]string{"a": "b"}))
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+		title  string
+	}{
+		{http.MethodGet, "/error", "", http.StatusInternalServerError, "Internal Server Error"},
+		{http.MethodGet, "/problem", "", http.StatusPaymentRequired, "Out of credit"},
+		{http.MethodPost, "/validate", "{}", http.StatusUnprocessableEntity, "Unprocessable Entity"},
+		{http.MethodPost, "/validate", "{", http.StatusBadRequest, "Bad Request"},
+		{http.MethodGet, "/auth", "", http.StatusUnauthorized, "Unauthorized"},
+		{http.MethodGet, "/missing", "", http.StatusNotFound, "Not Found"},
+		{http.MethodDelete, "/error", "", http.StatusMethodNotAllowed, "Method Not Allowed"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		var m map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+			t.Errorf("%s %s: %v: %q", tt.method, tt.path, err, rec.Body.String())
+			continue
+		}
+		if rec.Code != tt.code || rec.Header().Get("Content-Type") != "application/problem+json" ||
+			m["status"] != float64(tt.code) || m["title"] != tt.title {
+			t.Errorf("%s %s: got %d %q %v", tt.method, tt.path, rec.Code, rec.Header().Get("Content-Type"), m)
+		}
+		if tt.code == http.StatusUnprocessableEntity && m["code"] != "validation_failed" {
+			t.Errorf("%s %s: got %v", tt.method, tt.path, m)
+		}
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/medivhyang/deer
 
-go 1.14
+go 1.21
 
 require github.com/medivhyang/duck v0.0.10
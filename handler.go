@@ -2,12 +2,44 @@ package deer
 
 import "net/http"
 
+// HandlerFunc handles a request. The *Request and ResponseWriter come from a
+// pool and are reused once the handler returns, so they must not be retained
+// or used from goroutines that outlive it; copy the values you need instead.
 type HandlerFunc func(w ResponseWriter, r *Request)
 
 func (h HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h(WrapResponseWriter(w), WrapRequest(r))
+	r, st := withState(r)
+	req := acquireRequest(r)
+	req.st = st
+	defer releaseRequest(req)
+	rw, ok := w.(ResponseWriter)
+	if !ok {
+		pooled := acquireResponseWriter(w)
+		defer releaseResponseWriter(pooled)
+		rw = pooled.withFeatures()
+	}
+	h(rw, req)
 }
 
 func (h HandlerFunc) Next(w ResponseWriter, r *Request) {
-	h.ServeHTTP(w.Raw(), r.Raw)
+	h(w, r)
+}
+
+type ErrorHandlerFunc func(w ResponseWriter, r *Request) error
+
+func (h ErrorHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	HandlerFunc(h.handle).ServeHTTP(w, r)
+}
+
+func (h ErrorHandlerFunc) handle(w ResponseWriter, r *Request) {
+	if err := h(w, r); err != nil {
+		handleError(w, r, err)
+	}
+}
+
+func (h ErrorHandlerFunc) handlerFunc() HandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return h.handle
 }
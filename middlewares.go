@@ -1,15 +1,182 @@
 package deer
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Middleware = func(HandlerFunc) HandlerFunc
 
+type AccessLogFormat int
+
+const (
+	CommonLogFormat AccessLogFormat = iota
+	JSONLogFormat
+)
+
+type AccessLogOptions struct {
+	Format          AccessLogFormat
+	Writer          io.Writer
+	Logger          *slog.Logger
+	SampleRate      float64
+	SkipPaths       []string
+	RequestIDHeader string
+}
+
+type AccessLogRecord struct {
+	Time      time.Time     `json:"time"`
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Route     string        `json:"route,omitempty"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int           `json:"bytes"`
+	Latency   time.Duration `json:"latency_ns"`
+	RemoteIP  string        `json:"remote_ip"`
+	User      string        `json:"user,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+func AccessLog(options ...AccessLogOptions) Middleware {
+	var finalOptions AccessLogOptions
+	if len(options) > 0 {
+		finalOptions = options[0]
+	}
+	if finalOptions.RequestIDHeader == "" {
+		finalOptions.RequestIDHeader = "X-Request-ID"
+	}
+	skips := map[string]bool{}
+	for _, p := range finalOptions.SkipPaths {
+		skips[p] = true
+	}
+	var mu sync.Mutex
+	write := func(record *AccessLogRecord) {
+		var line []byte
+		switch finalOptions.Format {
+		case JSONLogFormat:
+			bs, err := json.Marshal(record)
+			if err != nil {
+				panic(err)
+			}
+			line = append(bs, '\n')
+		default:
+			line = []byte(record.common() + "\n")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if finalOptions.Writer == nil {
+			debugf("%s", bytes.TrimSuffix(line, []byte("\n")))
+			return
+		}
+		if _, err := finalOptions.Writer.Write(line); err != nil {
+			panic(err)
+		}
+	}
+	return func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			if skips[r.Path()] {
+				h.Next(w, r)
+				return
+			}
+			if rate := finalOptions.SampleRate; rate > 0 && rate < 1 && rand.Float64() >= rate {
+				h.Next(w, r)
+				return
+			}
+			start := time.Now()
+			defer func() {
+				panicked := recover()
+				record := &AccessLogRecord{
+					Time:      start,
+					Method:    r.Method(),
+					Path:      r.Raw.URL.RequestURI(),
+					Route:     r.Route(),
+					Proto:     r.Raw.Proto,
+					Status:    w.Status(),
+					Bytes:     w.Size(),
+					Latency:   time.Since(start),
+					RemoteIP:  r.ClientIP(),
+					UserAgent: r.Header("User-Agent"),
+					RequestID: r.Header(finalOptions.RequestIDHeader),
+				}
+				if record.Status == 0 {
+					record.Status = http.StatusOK
+					if panicked != nil {
+						// An outer Recovery answers 500 once the panic moves on.
+						record.Status = http.StatusInternalServerError
+					}
+				}
+				if record.RequestID == "" {
+					record.RequestID = w.Header().Get(finalOptions.RequestIDHeader)
+				}
+				if username, _, ok := r.BasicAuth(); ok {
+					record.User = username
+				}
+				if finalOptions.Logger != nil {
+					record.log(r.Context(), finalOptions.Logger)
+				} else {
+					write(record)
+				}
+				if panicked != nil {
+					panic(panicked)
+				}
+			}()
+			h.Next(w, r)
+		}
+	}
+}
+
+func (record *AccessLogRecord) common() string {
+	user := record.User
+	if user == "" {
+		user = "-"
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d",
+		record.RemoteIP,
+		user,
+		record.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		record.Method,
+		record.Path,
+		record.Proto,
+		record.Status,
+		record.Bytes,
+	)
+}
+
+func (record *AccessLogRecord) log(ctx context.Context, logger *slog.Logger) {
+	level := slog.LevelInfo
+	if record.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.LogAttrs(ctx, level, "access",
+		slog.String("method", record.Method),
+		slog.String("path", record.Path),
+		slog.String("route", record.Route),
+		slog.Int("status", record.Status),
+		slog.Int("bytes", record.Bytes),
+		slog.Duration("latency", record.Latency),
+		slog.String("remote_ip", record.RemoteIP),
+		slog.String("user", record.User),
+		slog.String("user_agent", record.UserAgent),
+		slog.String("request_id", record.RequestID),
+	)
+}
+
 func AllowedMethods(methods ...string) Middleware {
 	return func(h HandlerFunc) HandlerFunc {
 		return func(w ResponseWriter, r *Request) {
@@ -20,6 +187,11 @@ func AllowedMethods(methods ...string) Middleware {
 				}
 			}
 			if !find {
+				w.SetHeader("Allow", strings.Join(methods, ", "))
+				if r.problemDetails {
+					w.Problem(NewProblem(http.StatusMethodNotAllowed))
+					return
+				}
 				w.StatusCode(http.StatusMethodNotAllowed)
 				return
 			}
@@ -39,6 +211,8 @@ func BasicAuth(pairs map[string]string, realm ...string) Middleware {
 	}, realm...)
 }
 
+const BasicAuthUserKey = "deer.basic_auth.user"
+
 func BasicAuthWithFunc(f func(username, password string) bool, realm ...string) Middleware {
 	if f == nil {
 		panic(newError("basic auth with func", "require func"))
@@ -54,17 +228,121 @@ func BasicAuthWithFunc(f func(username, password string) bool, realm ...string)
 			username, password, ok := r.BasicAuth()
 			if ok {
 				if f(username, password) {
-					h.ServeHTTP(w.Raw(), r.Raw)
+					r.Set(BasicAuthUserKey, username)
+					h.Next(w, r)
 					return
 				}
 			}
-			w.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", finalRealm))
+			w.SetHeader("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", finalRealm))
+			if r.problemDetails {
+				w.Problem(NewProblem(http.StatusUnauthorized))
+				return
+			}
 			w.StatusCode(http.StatusUnauthorized)
 			return
 		}
 	}
 }
 
+// ErrRequestBodyTooLarge is returned by reads past a BodyLimit. It is an
+// *HTTPError so error handlers render it as 413.
+var ErrRequestBodyTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge, "request_body_too_large", "request body too large")
+
+type BodyLimitOptions struct {
+	Decompress bool
+}
+
+func BodyLimit(n int64, options ...BodyLimitOptions) Middleware {
+	if n < 0 {
+		panic(newError("body limit", "limit must not be negative"))
+	}
+	var finalOptions BodyLimitOptions
+	if len(options) > 0 {
+		finalOptions = options[0]
+	}
+	reject := func(w ResponseWriter, r *Request) {
+		w.SetHeader("Connection", "close")
+		handleError(w, r, ErrRequestBodyTooLarge)
+	}
+	return func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			body := r.Raw.Body
+			if body == nil || body == http.NoBody {
+				h.Next(w, r)
+				return
+			}
+			encoding := strings.ToLower(strings.TrimSpace(r.Header("Content-Encoding")))
+			decompress := finalOptions.Decompress && encoding != "" && encoding != "identity"
+			if !decompress && r.Raw.ContentLength > n {
+				reject(w, r)
+				return
+			}
+			var reader io.Reader = body
+			if decompress {
+				switch encoding {
+				case "gzip", "x-gzip":
+					zr, err := gzip.NewReader(body)
+					if err != nil {
+						handleError(w, r, NewHTTPError(http.StatusBadRequest, "", "").WithErr(err))
+						return
+					}
+					defer zr.Close()
+					reader = zr
+				case "deflate":
+					zr, err := zlib.NewReader(body)
+					if err != nil {
+						handleError(w, r, NewHTTPError(http.StatusBadRequest, "", "").WithErr(err))
+						return
+					}
+					defer zr.Close()
+					reader = zr
+				default:
+					handleError(w, r, NewHTTPError(http.StatusUnsupportedMediaType, "", ""))
+					return
+				}
+				r.Raw.Header.Del("Content-Encoding")
+				r.Raw.Header.Del("Content-Length")
+				r.Raw.ContentLength = -1
+			}
+			lb := &limitedBody{reader: reader, closer: body, remaining: n}
+			r.Raw.Body = lb
+			h.Next(w, r)
+			if lb.exceeded && !w.Written() {
+				reject(w, r)
+			}
+		}
+	}
+}
+
+type limitedBody struct {
+	reader    io.Reader
+	closer    io.Closer
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrRequestBodyTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.reader.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.exceeded = true
+		return n, ErrRequestBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.closer.Close()
+}
+
 type CORSOptions struct {
 	AllowOrigins     []string
 	AllowMethods     []string
@@ -87,38 +365,225 @@ func CORS(config ...CORSOptions) Middleware {
 	return func(h HandlerFunc) HandlerFunc {
 		return func(w ResponseWriter, r *Request) {
 			if len(finalConfig.AllowOrigins) > 0 {
-				w.Header("Access-Control-Allow-Origin", strings.Join(finalConfig.AllowOrigins, ","))
+				w.SetHeader("Access-Control-Allow-Origin", strings.Join(finalConfig.AllowOrigins, ","))
+			}
+			allowMethods := finalConfig.AllowMethods
+			if r.Method() == http.MethodOptions && r.HeaderExists("Access-Control-Request-Method") {
+				if routed := allowedMethodsFromContext(r.Context()); len(routed) > 0 {
+					allowMethods = corsAllowMethods(finalConfig.AllowMethods, routed)
+				}
 			}
-			if len(finalConfig.AllowMethods) > 0 {
-				w.Header("Access-Control-Allow-Methods", strings.Join(finalConfig.AllowMethods, ","))
+			if len(allowMethods) > 0 {
+				w.SetHeader("Access-Control-Allow-Methods", strings.Join(allowMethods, ","))
 			}
 			if len(finalConfig.AllowHeaders) > 0 {
-				w.Header("Access-Control-Allow-Headers", strings.Join(finalConfig.AllowHeaders, ","))
+				w.SetHeader("Access-Control-Allow-Headers", strings.Join(finalConfig.AllowHeaders, ","))
 			}
 			if len(finalConfig.ExposeHeaders) > 0 {
-				w.Header("Access-Control-Expose-Headers", strings.Join(finalConfig.ExposeHeaders, ","))
+				w.SetHeader("Access-Control-Expose-Headers", strings.Join(finalConfig.ExposeHeaders, ","))
 			}
 			if finalConfig.AllowCredentials {
-				w.Header("Access-Control-Allow-Credentials", "true")
+				w.SetHeader("Access-Control-Allow-Credentials", "true")
 			}
 			h.Next(w, r)
 		}
 	}
 }
 
-func MaxAllowed(n int) Middleware {
-	sem := make(chan struct{}, n)
-	acquire := func() { sem <- struct{}{} }
-	release := func() { <-sem }
-	return func(f HandlerFunc) HandlerFunc {
-		acquire()
-		defer release()
+func corsAllowMethods(configured []string, routed []string) []string {
+	if len(configured) == 0 || containsString(configured, "*") {
+		return routed
+	}
+	var result []string
+	for _, method := range routed {
+		if containsString(configured, method) {
+			result = append(result, method)
+		}
+	}
+	return result
+}
+
+type RateLimitOptions struct {
+	RateLimitRule
+	Key    func(r *Request) string
+	Store  RateLimitStore
+	Reject HandlerFunc
+}
+
+func RateLimit(options RateLimitOptions) Middleware {
+	if options.Limit <= 0 {
+		panic(newError("rate limit", "limit must be positive"))
+	}
+	if options.Window <= 0 {
+		options.Window = time.Minute
+	}
+	if options.Key == nil {
+		options.Key = RateLimitByIP()
+	}
+	if options.Store == nil {
+		options.Store = NewMemoryRateLimitStore()
+	}
+	if options.Reject == nil {
+		options.Reject = func(w ResponseWriter, r *Request) {
+			w.Text(http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+		}
+	}
+	return func(h HandlerFunc) HandlerFunc {
 		return func(w ResponseWriter, r *Request) {
-			f.Next(w, r)
+			result, err := options.Store.Take(options.Key(r), options.RateLimitRule)
+			if err != nil {
+				debugf("deer: rate limit: %s", err)
+				h.Next(w, r)
+				return
+			}
+			w.SetHeader("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.SetHeader("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.SetHeader("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				w.SetHeader("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				options.Reject(w, r)
+				return
+			}
+			h.Next(w, r)
 		}
 	}
 }
 
+func RateLimitByIP() func(r *Request) string {
+	return func(r *Request) string {
+		return r.ClientIP()
+	}
+}
+
+func RateLimitByBasicAuth() func(r *Request) string {
+	return func(r *Request) string {
+		if username, ok := Value[string](r, BasicAuthUserKey); ok {
+			return "user:" + username
+		}
+		return r.ClientIP()
+	}
+}
+
+func RateLimitByHeader(key string) func(r *Request) string {
+	return func(r *Request) string {
+		return r.Header(key)
+	}
+}
+
+func RateLimitByRoute() func(r *Request) string {
+	return func(r *Request) string {
+		return r.Method() + " " + r.Route()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type MaxAllowedOptions struct {
+	PerRoute   bool
+	Wait       time.Duration
+	QueueSize  int
+	RetryAfter time.Duration
+	Reject     HandlerFunc
+}
+
+type ConcurrencyLimiter struct {
+	n        int
+	options  MaxAllowedOptions
+	sem      chan struct{}
+	inFlight int64
+	waiting  int64
+	rejected int64
+}
+
+func NewConcurrencyLimiter(n int, options ...MaxAllowedOptions) *ConcurrencyLimiter {
+	if n <= 0 {
+		panic(newError("concurrency limiter", "limit must be positive"))
+	}
+	var finalOptions MaxAllowedOptions
+	if len(options) > 0 {
+		finalOptions = options[0]
+	}
+	if finalOptions.RetryAfter <= 0 {
+		finalOptions.RetryAfter = time.Second
+	}
+	if finalOptions.Reject == nil {
+		retryAfter := strconv.Itoa(ceilSeconds(finalOptions.RetryAfter))
+		finalOptions.Reject = func(w ResponseWriter, r *Request) {
+			w.SetHeader("Retry-After", retryAfter)
+			w.Text(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+		}
+	}
+	return &ConcurrencyLimiter{n: n, options: finalOptions, sem: make(chan struct{}, n)}
+}
+
+func (l *ConcurrencyLimiter) InFlight() int64 {
+	return atomic.LoadInt64(&l.inFlight)
+}
+
+func (l *ConcurrencyLimiter) Waiting() int64 {
+	return atomic.LoadInt64(&l.waiting)
+}
+
+func (l *ConcurrencyLimiter) Rejected() int64 {
+	return atomic.LoadInt64(&l.rejected)
+}
+
+func (l *ConcurrencyLimiter) Middleware() Middleware {
+	return func(h HandlerFunc) HandlerFunc {
+		sem := l.sem
+		if l.options.PerRoute {
+			sem = make(chan struct{}, l.n)
+		}
+		return func(w ResponseWriter, r *Request) {
+			if !l.acquire(sem, r) {
+				atomic.AddInt64(&l.rejected, 1)
+				if r.Context().Err() == nil {
+					l.options.Reject(w, r)
+				}
+				return
+			}
+			atomic.AddInt64(&l.inFlight, 1)
+			defer func() {
+				atomic.AddInt64(&l.inFlight, -1)
+				<-sem
+			}()
+			h.Next(w, r)
+		}
+	}
+}
+
+func (l *ConcurrencyLimiter) acquire(sem chan struct{}, r *Request) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+	}
+	if l.options.Wait <= 0 {
+		return false
+	}
+	if waiting := atomic.AddInt64(&l.waiting, 1); l.options.QueueSize > 0 && waiting > int64(l.options.QueueSize) {
+		atomic.AddInt64(&l.waiting, -1)
+		return false
+	}
+	defer atomic.AddInt64(&l.waiting, -1)
+	timer := time.NewTimer(l.options.Wait)
+	defer timer.Stop()
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func MaxAllowed(n int, options ...MaxAllowedOptions) Middleware {
+	return NewConcurrencyLimiter(n, options...).Middleware()
+}
+
 func Recovery(callback ...func(w ResponseWriter, r *Request, err interface{})) Middleware {
 	var f func(w ResponseWriter, r *Request, err interface{})
 	if len(callback) > 0 {
@@ -126,7 +591,7 @@ func Recovery(callback ...func(w ResponseWriter, r *Request, err interface{})) M
 	} else {
 		f = func(w ResponseWriter, r *Request, err interface{}) {
 			debugf("deer: recovery: %+v\n%s", err, string(debug.Stack()))
-			w.Text(http.StatusInternalServerError, fmt.Sprint(err))
+			handleError(w, r, panicError(err))
 		}
 	}
 	return func(h HandlerFunc) HandlerFunc {
@@ -136,11 +601,205 @@ func Recovery(callback ...func(w ResponseWriter, r *Request, err interface{})) M
 					f(w, r, err)
 				}
 			}()
-			h.ServeHTTP(w.Raw(), r.Raw)
+			h.Next(w, r)
 		}
 	}
 }
 
+type TimeoutOptions struct {
+	Handler HandlerFunc
+}
+
+func Timeout(d time.Duration, options ...TimeoutOptions) Middleware {
+	if d <= 0 {
+		panic(newError("timeout", "duration must be positive"))
+	}
+	var finalOptions TimeoutOptions
+	if len(options) > 0 {
+		finalOptions = options[0]
+	}
+	if finalOptions.Handler == nil {
+		finalOptions.Handler = func(w ResponseWriter, r *Request) {
+			w.Text(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+		}
+	}
+	return func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			if c, ok := r.Context().Value(timeoutContextKeySingleton).(*timeoutControl); ok {
+				c.reset(d)
+				h.Next(w, r)
+				return
+			}
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			c := newTimeoutControl(d, cancel)
+			defer c.stop()
+			r.SetContext(&timeoutContext{Context: ctx, control: c})
+			tw := &timeoutWriter{raw: w, header: http.Header{}}
+			tr := r.clone()
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if err := recover(); err != nil {
+						panicked <- err
+					}
+				}()
+				h.Next(WrapResponseWriter(tw), tr)
+				close(done)
+			}()
+			select {
+			case err := <-panicked:
+				tw.discard()
+				panic(err)
+			case <-done:
+				tw.flush()
+			case <-c.expired:
+				tw.discard()
+				finalOptions.Handler(w, r)
+			}
+		}
+	}
+}
+
+type timeoutContextKey struct{}
+
+var timeoutContextKeySingleton = timeoutContextKey{}
+
+type timeoutControl struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+	expired  chan struct{}
+	fired    bool
+	cancel   context.CancelFunc
+}
+
+func newTimeoutControl(d time.Duration, cancel context.CancelFunc) *timeoutControl {
+	c := &timeoutControl{expired: make(chan struct{}), cancel: cancel}
+	c.deadline = time.Now().Add(d)
+	c.timer = time.AfterFunc(d, c.fire)
+	return c
+}
+
+func (c *timeoutControl) fire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fired {
+		return
+	}
+	c.fired = true
+	close(c.expired)
+	c.cancel()
+}
+
+func (c *timeoutControl) reset(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fired || !c.timer.Stop() {
+		return
+	}
+	c.deadline = time.Now().Add(d)
+	c.timer = time.AfterFunc(d, c.fire)
+}
+
+func (c *timeoutControl) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer.Stop()
+}
+
+type timeoutContext struct {
+	context.Context
+	control *timeoutControl
+}
+
+func (ctx *timeoutContext) Deadline() (time.Time, bool) {
+	ctx.control.mu.Lock()
+	defer ctx.control.mu.Unlock()
+	if parent, ok := ctx.Context.Deadline(); ok && parent.Before(ctx.control.deadline) {
+		return parent, true
+	}
+	return ctx.control.deadline, true
+}
+
+func (ctx *timeoutContext) Err() error {
+	err := ctx.Context.Err()
+	if err == nil {
+		return nil
+	}
+	ctx.control.mu.Lock()
+	defer ctx.control.mu.Unlock()
+	if ctx.control.fired {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+func (ctx *timeoutContext) Value(key interface{}) interface{} {
+	if key == timeoutContextKeySingleton {
+		return ctx.control
+	}
+	return ctx.Context.Value(key)
+}
+
+type timeoutWriter struct {
+	mu         sync.Mutex
+	raw        http.ResponseWriter
+	header     http.Header
+	buf        bytes.Buffer
+	statusCode int
+	discarded  bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discarded || w.statusCode != 0 {
+		return
+	}
+	w.statusCode = statusCode
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discarded {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+func (w *timeoutWriter) discard() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.discarded = true
+}
+
+func (w *timeoutWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.discarded = true
+	header := w.raw.Header()
+	for k, v := range w.header {
+		header[k] = v
+	}
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	w.raw.WriteHeader(w.statusCode)
+	if _, err := w.raw.Write(w.buf.Bytes()); err != nil {
+		panic(err)
+	}
+}
+
 func Timing(callback ...func(w ResponseWriter, r *Request, d time.Duration)) Middleware {
 	var f func(w ResponseWriter, r *Request, d time.Duration)
 	if len(callback) > 0 {
@@ -0,0 +1,384 @@
+package deer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAccessLogCommon(t *testing.T) {
+	var buf bytes.Buffer
+	router := NewRouter().Use(AccessLog(AccessLogOptions{Writer: &buf, SkipPaths: []string{"/health"}}))
+	router.Get("/users/:id", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusCreated, "hello")
+	})
+	router.Get("/health", echoRoute)
+	serve(router, http.MethodGet, "/health")
+	serve(router, http.MethodGet, "/users/1?x=y")
+	serve(router, http.MethodGet, "/missing")
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`^192\.0\.2\.1 - - \[[^\]]+\] "GET /users/1\?x=y HTTP/1\.1" 201 5$`),
+		regexp.MustCompile(`^192\.0\.2\.1 - - \[[^\]]+\] "GET /missing HTTP/1\.1" 404 9$`),
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got lines %q", lines)
+	}
+	for i, re := range want {
+		if !re.MatchString(lines[i]) {
+			t.Errorf("line %d: got %q, want %s", i, lines[i], re)
+		}
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	router := NewRouter().Use(AccessLog(AccessLogOptions{Writer: &buf, Format: JSONLogFormat}))
+	router.Get("/users/:id", func(w ResponseWriter, r *Request) {
+		w.SetHeader("X-Request-ID", "abc")
+	})
+	serve(router, http.MethodGet, "/users/7", "User-Agent", "test")
+	var record AccessLogRecord
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	if record.Method != http.MethodGet || record.Path != "/users/7" || record.Route != "/users/:id" ||
+		record.Status != http.StatusOK || record.Bytes != 0 || record.UserAgent != "test" || record.RequestID != "abc" {
+		t.Errorf("got %+v", record)
+	}
+}
+
+func TestAccessLogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	router := NewRouter().Use(AccessLog(AccessLogOptions{Logger: logger}))
+	router.Get("/boom", func(w ResponseWriter, r *Request) {
+		w.StatusCode(http.StatusBadGateway)
+	})
+	serve(router, http.MethodGet, "/boom")
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	if m["level"] != "ERROR" || m["msg"] != "access" || m["status"] != float64(http.StatusBadGateway) || m["route"] != "/boom" {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestAccessLogPanic(t *testing.T) {
+	var buf bytes.Buffer
+	Debug(true)
+	Output(&buf)
+	defer func() {
+		Debug(false)
+		Output(os.Stdout)
+	}()
+	inner := NewRouter().Use(Recovery(), AccessLog(AccessLogOptions{Writer: &buf}))
+	tests := []struct {
+		name   string
+		router *Router
+	}{
+		{"default", Default()},
+		{"inside recovery", inner},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		tt.router.Get("/p", func(w ResponseWriter, r *Request) {
+			panic("boom")
+		})
+		rec := serve(tt.router, http.MethodGet, "/p")
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(buf.String(), `"GET /p HTTP/1.1" 500 `) {
+			t.Errorf("%s: got %d, log %q", tt.name, rec.Code, buf.String())
+		}
+	}
+}
+
+func blockingRouter(mws ...Middleware) (*Router, chan struct{}, chan struct{}) {
+	entered := make(chan struct{}, 16)
+	release := make(chan struct{})
+	router := NewRouter().Use(mws...)
+	handler := func(w ResponseWriter, r *Request) {
+		entered <- struct{}{}
+		<-release
+		w.Text(http.StatusOK, "ok")
+	}
+	router.Get("/a", handler)
+	router.Get("/b", handler)
+	return router, entered, release
+}
+
+func TestMaxAllowedReject(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1)
+	router, entered, release := blockingRouter(limiter.Middleware())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(router, http.MethodGet, "/a")
+	}()
+	<-entered
+	rec := serve(router, http.MethodGet, "/b")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("got %d retry-after %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if limiter.InFlight() != 1 || limiter.Rejected() != 1 {
+		t.Errorf("got in flight %d rejected %d", limiter.InFlight(), limiter.Rejected())
+	}
+	close(release)
+	wg.Wait()
+	if limiter.InFlight() != 0 {
+		t.Errorf("got in flight %d after release", limiter.InFlight())
+	}
+}
+
+func TestMaxAllowedWait(t *testing.T) {
+	router, entered, release := blockingRouter(MaxAllowed(1, MaxAllowedOptions{Wait: time.Second}))
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve(router, http.MethodGet, "/a").Code
+		}()
+	}
+	<-entered
+	select {
+	case <-entered:
+		t.Fatal("second request entered while the first holds the slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release <- struct{}{}
+	<-entered
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("got %d", code)
+		}
+	}
+}
+
+func TestMaxAllowedPerRoute(t *testing.T) {
+	router, entered, release := blockingRouter(MaxAllowed(1, MaxAllowedOptions{PerRoute: true}))
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	for _, p := range []string{"/a", "/b"} {
+		p := p
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve(router, http.MethodGet, p).Code
+		}()
+	}
+	<-entered
+	<-entered
+	if rec := serve(router, http.MethodGet, "/a"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("third request: got %d", rec.Code)
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("got %d", code)
+		}
+	}
+}
+
+func TestMaxAllowedCanceled(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, MaxAllowedOptions{Wait: time.Minute})
+	router, entered, release := blockingRouter(limiter.Middleware())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(router, http.MethodGet, "/a")
+	}()
+	<-entered
+	req := httptest.NewRequest(http.MethodGet, "/a", nil)
+	ctx, cancel := context.WithCancel(req.Context())
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(rec, req.WithContext(ctx))
+		close(done)
+	}()
+	for limiter.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if limiter.Rejected() != 1 || rec.Body.Len() != 0 {
+		t.Errorf("got rejected %d body %q", limiter.Rejected(), rec.Body.String())
+	}
+	close(release)
+	wg.Wait()
+}
+
+func TestRateLimit(t *testing.T) {
+	router := NewRouter().Use(RateLimit(RateLimitOptions{RateLimitRule: RateLimitRule{Limit: 2, Window: time.Minute}}))
+	router.Get("/", echoRoute)
+	for i := 0; i < 2; i++ {
+		if rec := serve(router, http.MethodGet, "/"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "2" {
+			t.Fatalf("request %d: got %d headers %v", i, rec.Code, rec.Header())
+		}
+	}
+	rec := serve(router, http.MethodGet, "/")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("over limit: got %d headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRateLimitByBasicAuth(t *testing.T) {
+	router := NewRouter().Use(
+		BasicAuthWithFunc(func(username, password string) bool { return password == "secret" }),
+		RateLimit(RateLimitOptions{RateLimitRule: RateLimitRule{Limit: 1, Window: time.Minute}, Key: RateLimitByBasicAuth()}),
+	)
+	router.Get("/", echoRoute)
+	request := func(username string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(username, "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := request("alice"); code != http.StatusOK {
+		t.Fatalf("alice: got %d", code)
+	}
+	if code := request("alice"); code != http.StatusTooManyRequests {
+		t.Errorf("alice again: got %d", code)
+	}
+	if code := request("bob"); code != http.StatusOK {
+		t.Errorf("bob: got %d", code)
+	}
+
+	unverified := NewRouter().Use(RateLimit(RateLimitOptions{RateLimitRule: RateLimitRule{Limit: 1, Window: time.Minute}, Key: RateLimitByBasicAuth()}))
+	unverified.Get("/", echoRoute)
+	for i, username := range []string{"x1", "x2"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(username, "whatever")
+		rec := httptest.NewRecorder()
+		unverified.ServeHTTP(rec, req)
+		if want := []int{http.StatusOK, http.StatusTooManyRequests}[i]; rec.Code != want {
+			t.Errorf("unverified %s: got %d, want %d", username, rec.Code, want)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	sleep := func(d time.Duration) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			w.SetHeader("X-Partial", "1")
+			w.Text(http.StatusOK, "done")
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+			}
+		}
+	}
+	router := NewRouter().Use(Timeout(20 * time.Millisecond))
+	router.Get("/fast", sleep(0))
+	router.Get("/slow", sleep(time.Second))
+	router.Get("/override", Timeout(time.Second)(sleep(50*time.Millisecond)))
+	router.Get("/deadline", func(w ResponseWriter, r *Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok || time.Until(deadline) > 20*time.Millisecond {
+			t.Errorf("deadline %v %v", deadline, ok)
+		}
+		<-r.Context().Done()
+		if r.Context().Err() != context.DeadlineExceeded {
+			t.Errorf("got err %v", r.Context().Err())
+		}
+	})
+	tests := []struct {
+		path    string
+		code    int
+		body    string
+		partial string
+	}{
+		{"/fast", http.StatusOK, "done", "1"},
+		{"/slow", http.StatusServiceUnavailable, "Service Unavailable", ""},
+		{"/override", http.StatusOK, "done", "1"},
+		{"/deadline", http.StatusServiceUnavailable, "Service Unavailable", ""},
+	}
+	for _, tt := range tests {
+		rec := serve(router, http.MethodGet, tt.path)
+		if rec.Code != tt.code || rec.Body.String() != tt.body || rec.Header().Get("X-Partial") != tt.partial {
+			t.Errorf("GET %s: got %d %q partial %q", tt.path, rec.Code, rec.Body.String(), rec.Header().Get("X-Partial"))
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	router := NewRouter().Use(BodyLimit(10, BodyLimitOptions{Decompress: true}))
+	router.PostE("/json", func(w ResponseWriter, r *Request) error {
+		var v map[string]string
+		if err := r.BindJSON(&v); err != nil {
+			return err
+		}
+		w.Text(http.StatusOK, "ok")
+		return nil
+	})
+	router.Post("/ignore", func(w ResponseWriter, r *Request) {
+		_, err := io.ReadAll(r.Raw.Body)
+		if !errors.Is(err, ErrRequestBodyTooLarge) {
+			t.Errorf("read error %v", err)
+		}
+	})
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.Bytes()
+	}
+	tests := []struct {
+		name     string
+		path     string
+		body     []byte
+		chunked  bool
+		encoding string
+		code     int
+		close    bool
+	}{
+		{"within limit", "/json", []byte(`{"a":"1"}`), false, "", http.StatusOK, false},
+		{"content length", "/json", []byte(`{"a":"123456"}`), false, "", http.StatusRequestEntityTooLarge, true},
+		{"chunked", "/json", []byte(`{"a":"123456"}`), true, "", http.StatusRequestEntityTooLarge, false},
+		{"ignored error", "/ignore", []byte("12345678901"), true, "", http.StatusRequestEntityTooLarge, true},
+		{"gzip within limit", "/json", gzipped(`{"a":"1"}`), false, "gzip", http.StatusOK, false},
+		{"gzip bomb", "/json", gzipped(`{"a":"` + strings.Repeat("a", 1000) + `"}`), false, "gzip", http.StatusRequestEntityTooLarge, false},
+		{"bad gzip", "/json", []byte("nope"), false, "gzip", http.StatusBadRequest, false},
+		{"unknown encoding", "/json", []byte("{}"), false, "br", http.StatusUnsupportedMediaType, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body))
+		if tt.chunked {
+			req.ContentLength = -1
+		}
+		if tt.encoding != "" {
+			req.Header.Set("Content-Encoding", tt.encoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s: got %d %q, want %d", tt.name, rec.Code, rec.Body.String(), tt.code)
+		}
+		if tt.close && rec.Header().Get("Connection") != "close" {
+			t.Errorf("%s: got connection %q", tt.name, rec.Header().Get("Connection"))
+		}
+	}
+}
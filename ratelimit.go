@@ -0,0 +1,138 @@
+package deer
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type RateLimitAlgorithm int
+
+const (
+	TokenBucket RateLimitAlgorithm = iota
+	SlidingWindow
+)
+
+type RateLimitRule struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Burst     int
+	Window    time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type RateLimitStore interface {
+	Take(key string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+type rateLimitState struct {
+	tokens    float64
+	last      time.Time
+	start     time.Time
+	prev      int
+	curr      int
+	expiresAt time.Time
+}
+
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	states    map[string]*rateLimitState
+	interval  time.Duration
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore(sweepInterval ...time.Duration) *MemoryRateLimitStore {
+	interval := time.Minute
+	if len(sweepInterval) > 0 && sweepInterval[0] > 0 {
+		interval = sweepInterval[0]
+	}
+	return &MemoryRateLimitStore{states: map[string]*rateLimitState{}, interval: interval}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, rule RateLimitRule) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= s.interval {
+		for k, v := range s.states {
+			if now.After(v.expiresAt) {
+				delete(s.states, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	state := s.states[key]
+	if state == nil {
+		state = &rateLimitState{}
+		s.states[key] = state
+	}
+	var result RateLimitResult
+	switch rule.Algorithm {
+	case SlidingWindow:
+		result = state.slidingWindow(rule, now)
+	default:
+		result = state.tokenBucket(rule, now)
+	}
+	state.expiresAt = now.Add(2 * rule.Window)
+	return result, nil
+}
+
+func (s *rateLimitState) tokenBucket(rule RateLimitRule, now time.Time) RateLimitResult {
+	capacity := float64(rule.Burst)
+	if capacity <= 0 {
+		capacity = float64(rule.Limit)
+	}
+	rate := float64(rule.Limit) / rule.Window.Seconds()
+	if s.last.IsZero() {
+		s.tokens = capacity
+	} else {
+		s.tokens = math.Min(capacity, s.tokens+now.Sub(s.last).Seconds()*rate)
+	}
+	s.last = now
+	result := RateLimitResult{Limit: int(capacity)}
+	if s.tokens >= 1 {
+		s.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - s.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(s.tokens)
+	result.Reset = time.Duration((capacity - s.tokens) / rate * float64(time.Second))
+	return result
+}
+
+func (s *rateLimitState) slidingWindow(rule RateLimitRule, now time.Time) RateLimitResult {
+	if s.start.IsZero() {
+		s.start = now
+	}
+	if elapsed := now.Sub(s.start); elapsed >= rule.Window {
+		windows := int64(elapsed / rule.Window)
+		if windows == 1 {
+			s.prev = s.curr
+		} else {
+			s.prev = 0
+		}
+		s.curr = 0
+		s.start = s.start.Add(time.Duration(windows) * rule.Window)
+	}
+	elapsed := now.Sub(s.start)
+	weight := 1 - float64(elapsed)/float64(rule.Window)
+	estimated := float64(s.prev)*weight + float64(s.curr)
+	result := RateLimitResult{Limit: rule.Limit, Reset: rule.Window - elapsed}
+	if estimated+1 <= float64(rule.Limit) {
+		s.curr++
+		estimated++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = rule.Window - elapsed
+	}
+	result.Remaining = int(math.Max(0, float64(rule.Limit)-math.Ceil(estimated)))
+	return result
+}
@@ -0,0 +1,64 @@
+package deer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	rule := RateLimitRule{Algorithm: TokenBucket, Limit: 2, Burst: 3, Window: time.Second}
+	var state rateLimitState
+	now := time.Unix(0, 0)
+	for i := 0; i < 3; i++ {
+		if result := state.tokenBucket(rule, now); !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+			t.Fatalf("take %d: got %+v", i, result)
+		}
+	}
+	result := state.tokenBucket(rule, now)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("over burst: got %+v", result)
+	}
+	if result := state.tokenBucket(rule, now.Add(500*time.Millisecond)); !result.Allowed {
+		t.Fatalf("after refill: got %+v", result)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	rule := RateLimitRule{Algorithm: SlidingWindow, Limit: 2, Window: time.Second}
+	var state rateLimitState
+	now := time.Unix(0, 0)
+	for i := 0; i < 2; i++ {
+		if result := state.slidingWindow(rule, now); !result.Allowed {
+			t.Fatalf("take %d: got %+v", i, result)
+		}
+	}
+	if result := state.slidingWindow(rule, now.Add(100*time.Millisecond)); result.Allowed || result.RetryAfter != 900*time.Millisecond {
+		t.Fatalf("over limit: got %+v", result)
+	}
+	if result := state.slidingWindow(rule, now.Add(1250*time.Millisecond)); result.Allowed {
+		t.Fatalf("previous window still weighs 75%%: got %+v", result)
+	}
+	if result := state.slidingWindow(rule, now.Add(1600*time.Millisecond)); !result.Allowed {
+		t.Fatalf("previous window weighs 40%%: got %+v", result)
+	}
+	if result := state.slidingWindow(rule, now.Add(5*time.Second)); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("after idle windows: got %+v", result)
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	store := NewMemoryRateLimitStore(time.Nanosecond)
+	rule := RateLimitRule{Limit: 1, Window: time.Nanosecond}
+	if _, err := store.Take("a", rule); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := store.Take("b", rule); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.states["a"]; ok || len(store.states) != 1 {
+		t.Errorf("got states %v", store.states)
+	}
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/medivhyang/duck/naming"
 	"github.com/medivhyang/duck/reflectutil"
@@ -14,13 +20,29 @@ const (
 	bindingTagKey = "binding"
 )
 
+var requestPool = sync.Pool{New: func() interface{} { return &Request{} }}
+
 func WrapRequest(r *http.Request) *Request {
 	return &Request{Raw: r}
 }
 
+func acquireRequest(r *http.Request) *Request {
+	req := requestPool.Get().(*Request)
+	req.Raw = r
+	return req
+}
+
+func releaseRequest(r *Request) {
+	*r = Request{}
+	requestPool.Put(r)
+}
+
 type Request struct {
-	Raw    *http.Request
-	params map[string]string
+	Raw            *http.Request
+	st             *requestState
+	errorHandler   func(w ResponseWriter, r *Request, err error)
+	problemDetails bool
+	validator      Validator
 }
 
 func (r *Request) Context() context.Context {
@@ -31,6 +53,54 @@ func (r *Request) SetContext(ctx context.Context) {
 	r.Raw = r.Raw.WithContext(ctx)
 }
 
+func (r *Request) Set(key string, value interface{}) {
+	s := r.state()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = map[string]interface{}{}
+	}
+	s.values[key] = value
+}
+
+func (r *Request) Get(key string) (interface{}, bool) {
+	s := r.state()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// state returns the router's per-request state, attaching a fresh one to the
+// context when the request did not come through a router.
+func (r *Request) state() *requestState {
+	if r.st == nil {
+		r.st = stateFromContext(r.Context())
+	}
+	if r.st == nil {
+		r.Raw, r.st = withState(r.Raw)
+	}
+	return r.st
+}
+
+func Value[T any](r *Request, key string) (T, bool) {
+	var zero T
+	value, ok := r.Get(key)
+	if !ok {
+		return zero, false
+	}
+	result, ok := value.(T)
+	if !ok {
+		return zero, false
+	}
+	return result, true
+}
+
+func (r *Request) clone() *Request {
+	c := *r
+	return &c
+}
+
 func (r *Request) Method() string {
 	return r.Raw.Method
 }
@@ -39,6 +109,18 @@ func (r *Request) Path() string {
 	return r.Raw.URL.Path
 }
 
+func (r *Request) Route() string {
+	return Route(r.Raw)
+}
+
+func (r *Request) ClientIP() string {
+	host, _, err := net.SplitHostPort(r.Raw.RemoteAddr)
+	if err != nil {
+		return r.Raw.RemoteAddr
+	}
+	return host
+}
+
 func (r *Request) Header(key string) string {
 	return r.Raw.Header.Get(key)
 }
@@ -100,17 +182,12 @@ func (r *Request) CookieExists(key string) bool {
 }
 
 func (r *Request) Param(key string) string {
-	if r.params == nil {
-		r.params = Params(r.Raw)
-	}
-	return r.params[key]
+	p, _ := r.state().param(key)
+	return p.value
 }
 
 func (r *Request) ParamOrDefault(key string, value string) string {
-	if r.params == nil {
-		r.params = Params(r.Raw)
-	}
-	result := r.params[key]
+	result := r.Param(key)
 	if result == "" {
 		return value
 	}
@@ -118,13 +195,34 @@ func (r *Request) ParamOrDefault(key string, value string) string {
 }
 
 func (r *Request) ParamExists(key string) bool {
-	if r.params == nil {
-		r.params = Params(r.Raw)
-	}
-	_, ok := r.params[key]
+	_, ok := r.state().param(key)
 	return ok
 }
 
+func (r *Request) ParamInt(key string) (int, error) {
+	p, _ := r.state().param(key)
+	if v, ok := p.typed.(int); ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(p.value)
+	if err != nil {
+		return 0, decodeError(err, true)
+	}
+	return v, nil
+}
+
+func (r *Request) ParamUUID(key string) (UUID, error) {
+	p, _ := r.state().param(key)
+	if v, ok := p.typed.(UUID); ok {
+		return v, nil
+	}
+	v, err := ParseUUID(p.value)
+	if err != nil {
+		return UUID{}, decodeError(err, true)
+	}
+	return v, nil
+}
+
 func (r *Request) Query(key string) string {
 	return r.Raw.URL.Query().Get(key)
 }
@@ -170,53 +268,113 @@ func (r *Request) FormExists(key string) bool {
 }
 
 func (r *Request) BindJSON(i interface{}) error {
-	return json.NewDecoder(r.Raw.Body).Decode(i)
+	if err := json.NewDecoder(r.Raw.Body).Decode(i); err != nil {
+		return decodeError(err, false)
+	}
+	return r.validate(i, jsonNaming)
 }
 
 func (r *Request) BindXML(i interface{}) error {
-	return xml.NewDecoder(r.Raw.Body).Decode(i)
+	if err := xml.NewDecoder(r.Raw.Body).Decode(i); err != nil {
+		return decodeError(err, false)
+	}
+	return r.validate(i, xmlNaming)
 }
 
 func (r *Request) BindQuery(i interface{}) error {
 	m := reflectutil.ParseStructTag(i, bindingTagKey)
-	return reflectutil.BindStructFunc(i, func(s string) []string {
+	if err := reflectutil.BindStructFunc(i, func(s string) []string {
 		if v, ok := m[s]; ok {
 			s = v
 		} else {
 			s = naming.ToSnake(s)
 		}
 		return r.Raw.URL.Query()[s]
-	})
+	}); err != nil {
+		return decodeError(err, false)
+	}
+	return r.validate(i, bindingNaming)
 }
 
 func (r *Request) BindPostForm(i interface{}) error {
 	if err := r.Raw.ParseForm(); err != nil {
-		return err
+		return decodeError(err, true)
 	}
 	m := reflectutil.ParseStructTag(i, bindingTagKey)
-	return reflectutil.BindStructFunc(i, func(s string) []string {
+	if err := reflectutil.BindStructFunc(i, func(s string) []string {
 		if v, ok := m[s]; ok {
 			s = v
 		} else {
 			s = naming.ToSnake(s)
 		}
 		return r.Raw.PostForm[s]
-	})
+	}); err != nil {
+		return decodeError(err, false)
+	}
+	return r.validate(i, bindingNaming)
 }
 
 func (r *Request) BindForm(i interface{}) error {
 	if err := r.Raw.ParseForm(); err != nil {
-		return err
+		return decodeError(err, true)
 	}
 	m := reflectutil.ParseStructTag(i, bindingTagKey)
-	return reflectutil.BindStructFunc(i, func(s string) []string {
+	if err := reflectutil.BindStructFunc(i, func(s string) []string {
 		if v, ok := m[s]; ok {
 			s = v
 		} else {
 			s = naming.ToSnake(s)
 		}
 		return r.Raw.Form[s]
-	})
+	}); err != nil {
+		return decodeError(err, false)
+	}
+	return r.validate(i, bindingNaming)
+}
+
+// decodeError reports a failure to decode the request as an *HTTPError: 413
+// for oversized bodies and 400 for malformed input. With malformed false,
+// only errors known to come from bad input count as malformed, so mistakes
+// such as binding into a non-pointer stay server errors.
+func decodeError(err error, malformed bool) error {
+	var (
+		httpErr         *HTTPError
+		maxBytesErr     *http.MaxBytesError
+		jsonSyntaxErr   *json.SyntaxError
+		jsonTypeErr     *json.UnmarshalTypeError
+		xmlSyntaxErr    *xml.SyntaxError
+		xmlUnmarshalErr xml.UnmarshalError
+		numErr          *strconv.NumError
+		timeParseErr    *time.ParseError
+	)
+	switch {
+	case errors.As(err, &httpErr):
+		return err
+	case errors.As(err, &maxBytesErr):
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "", "").WithErr(err)
+	case malformed,
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &jsonSyntaxErr),
+		errors.As(err, &jsonTypeErr),
+		errors.As(err, &xmlSyntaxErr),
+		errors.As(err, &xmlUnmarshalErr),
+		errors.As(err, &numErr),
+		errors.As(err, &timeParseErr):
+		return NewHTTPError(http.StatusBadRequest, "", "").WithErr(err)
+	}
+	return err
+}
+
+func (r *Request) validate(i interface{}, names fieldNaming) error {
+	v := r.validator
+	if v == nil {
+		v = DefaultValidator
+	}
+	if tv, ok := v.(*tagValidator); ok {
+		return tv.validateNamed(i, names)
+	}
+	return v.Validate(i)
 }
 
 func (r *Request) BasicAuth() (username string, password string, ok bool) {
@@ -0,0 +1,89 @@
+package deer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestStore(t *testing.T) {
+	router := NewRouter().Use(func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			r.Set("user", "alice")
+			r.Set("count", 1)
+			h.Next(w, r)
+		}
+	})
+	router.Get("/", func(w ResponseWriter, r *Request) {
+		if v, ok := r.Get("user"); !ok || v != "alice" {
+			t.Errorf("get user: got %v %v", v, ok)
+		}
+		if _, ok := r.Get("missing"); ok {
+			t.Error("get missing: want false")
+		}
+		if v, ok := Value[string](r, "user"); !ok || v != "alice" {
+			t.Errorf("value user: got %q %v", v, ok)
+		}
+		if v, ok := Value[int](r, "count"); !ok || v != 1 {
+			t.Errorf("value count: got %d %v", v, ok)
+		}
+		if v, ok := Value[string](r, "count"); ok || v != "" {
+			t.Errorf("value count as string: got %q %v", v, ok)
+		}
+	})
+	serve(router, http.MethodGet, "/")
+}
+
+func TestBasicAuthUserKey(t *testing.T) {
+	router := NewRouter().Use(BasicAuth(map[string]string{"alice": "secret"}))
+	router.Get("/", func(w ResponseWriter, r *Request) {
+		user, _ := Value[string](r, BasicAuthUserKey)
+		w.Text(http.StatusOK, user)
+	})
+	tests := []struct {
+		username string
+		password string
+		code     int
+		body     string
+	}{
+		{"alice", "secret", http.StatusOK, "alice"},
+		{"alice", "wrong", http.StatusUnauthorized, ""},
+		{"mallory", "secret", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(tt.username, tt.password)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.code || rec.Body.String() != tt.body {
+			t.Errorf("%s:%s: got %d %q", tt.username, tt.password, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestRequestStoreShared(t *testing.T) {
+	child := NewRouter()
+	child.Get("/set", func(w ResponseWriter, r *Request) {
+		r.Set("from", "child")
+	})
+	router := NewRouter().Use(func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			h.Next(w, r)
+			from, _ := Value[string](r, "from")
+			w.Text(http.StatusOK, from)
+		}
+	})
+	router.Get("/timeout", Timeout(time.Second)(func(w ResponseWriter, r *Request) {
+		r.Set("from", "timeout")
+	}))
+	router.Mount("/child", child)
+	for _, tt := range []struct{ path, body string }{
+		{"/timeout", "timeout"},
+		{"/child/set", "child"},
+	} {
+		if rec := serve(router, http.MethodGet, tt.path); rec.Body.String() != tt.body {
+			t.Errorf("GET %s: got %q, want %q", tt.path, rec.Body.String(), tt.body)
+		}
+	}
+}
@@ -1,71 +1,285 @@
 package deer
 
 import (
+	"bufio"
 	"encoding/json"
 	"encoding/xml"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 )
 
+// ResponseWriter implements http.Flusher, http.Hijacker and http.Pusher
+// exactly when the writer it wraps does, so type assertions keep working for
+// feature detection.
 type ResponseWriter interface {
+	http.ResponseWriter
 	Raw() http.ResponseWriter
+	Status() int
+	Size() int
+	Written() bool
 	StatusCode(statusCode int)
-	Header(key string, value string) ResponseWriter
+	SetHeader(key string, value string) ResponseWriter
 	Text(statusCode int, text string)
 	HTML(statusCode int, content string)
 	JSON(statusCode int, value interface{})
 	XML(statusCode int, value interface{})
+	Problem(p *Problem)
 }
 
 type responseWriter struct {
-	raw http.ResponseWriter
+	raw        http.ResponseWriter
+	statusCode int
+	size       int
+	written    bool
 }
 
+var responseWriterPool = sync.Pool{New: func() interface{} { return &responseWriter{} }}
+
 func WrapResponseWriter(w http.ResponseWriter) ResponseWriter {
-	return &responseWriter{raw: w}
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	return (&responseWriter{raw: w}).withFeatures()
+}
+
+func acquireResponseWriter(raw http.ResponseWriter) *responseWriter {
+	w := responseWriterPool.Get().(*responseWriter)
+	w.raw = raw
+	return w
+}
+
+func releaseResponseWriter(w *responseWriter) {
+	*w = responseWriter{}
+	responseWriterPool.Put(w)
 }
 
 func (w *responseWriter) Raw() http.ResponseWriter {
 	return w.raw
 }
 
-func (w *responseWriter) StatusCode(statusCode int) {
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.raw
+}
+
+func (w *responseWriter) Status() int {
+	return w.statusCode
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.raw.Header()
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.written {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		w.raw.WriteHeader(statusCode)
+		return
+	}
+	w.written = true
+	w.statusCode = statusCode
 	w.raw.WriteHeader(statusCode)
 }
 
-func (w *responseWriter) Header(key string, value string) ResponseWriter {
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.raw.Write(b)
+	w.size += n
+	return n, err
+}
+
+// withFeatures picks the variant that adds exactly the optional interfaces
+// the raw writer supports. Each variant holds a single pointer, so storing it
+// in an interface does not allocate.
+func (w *responseWriter) withFeatures() ResponseWriter {
+	flush, hijack, push := writerFeatures(w.raw)
+	switch {
+	case flush && hijack && push:
+		return flushHijackPushWriter{w}
+	case flush && hijack:
+		return flushHijackWriter{w}
+	case flush && push:
+		return flushPushWriter{w}
+	case hijack && push:
+		return hijackPushWriter{w}
+	case flush:
+		return flushWriter{w}
+	case hijack:
+		return hijackWriter{w}
+	case push:
+		return pushWriter{w}
+	}
+	return w
+}
+
+func writerFeatures(w http.ResponseWriter) (flush bool, hijack bool, push bool) {
+	if f, ok := w.(interface{ features() (bool, bool, bool) }); ok {
+		return f.features()
+	}
+	_, flush = w.(http.Flusher)
+	_, hijack = w.(http.Hijacker)
+	_, push = w.(http.Pusher)
+	return
+}
+
+func (w *responseWriter) flush() {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.raw.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.raw.(http.Hijacker).Hijack()
+	if err == nil && !w.written {
+		w.written = true
+		w.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) push(target string, opts *http.PushOptions) error {
+	return w.raw.(http.Pusher).Push(target, opts)
+}
+
+type flushWriter struct{ *responseWriter }
+
+func (w flushWriter) Flush() { w.flush() }
+
+type hijackWriter struct{ *responseWriter }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type pushWriter struct{ *responseWriter }
+
+func (w pushWriter) Push(target string, opts *http.PushOptions) error { return w.push(target, opts) }
+
+type flushHijackWriter struct{ *responseWriter }
+
+func (w flushHijackWriter) Flush() { w.flush() }
+
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type flushPushWriter struct{ *responseWriter }
+
+func (w flushPushWriter) Flush() { w.flush() }
+
+func (w flushPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type hijackPushWriter struct{ *responseWriter }
+
+func (w hijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+func (w hijackPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type flushHijackPushWriter struct{ *responseWriter }
+
+func (w flushHijackPushWriter) Flush() { w.flush() }
+
+func (w flushHijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+func (w flushHijackPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+func (w *responseWriter) StatusCode(statusCode int) {
+	w.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) SetHeader(key string, value string) ResponseWriter {
 	w.raw.Header().Set(key, value)
 	return w
 }
 
 func (w *responseWriter) Text(statusCode int, text string) {
 	w.raw.Header().Set("Content-Type", "text/plain")
-	w.raw.WriteHeader(statusCode)
-	if _, err := io.WriteString(w.raw, text); err != nil {
+	w.WriteHeader(statusCode)
+	if _, err := io.WriteString(w, text); err != nil {
 		panic(err)
 	}
 }
 
 func (w *responseWriter) HTML(statusCode int, content string) {
 	w.raw.Header().Set("Content-Type", "text/html")
-	w.raw.WriteHeader(statusCode)
-	if _, err := io.WriteString(w.raw, content); err != nil {
+	w.WriteHeader(statusCode)
+	if _, err := io.WriteString(w, content); err != nil {
 		panic(err)
 	}
 }
 
 func (w *responseWriter) JSON(statusCode int, value interface{}) {
 	w.raw.Header().Set("Content-Type", "application/json")
-	w.raw.WriteHeader(statusCode)
-	if err := json.NewEncoder(w.raw).Encode(value); err != nil {
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
 		panic(err)
 	}
 }
 
 func (w *responseWriter) XML(statusCode int, value interface{}) {
 	w.raw.Header().Set("Content-Type", "application/xml")
-	w.raw.WriteHeader(statusCode)
-	if err := xml.NewEncoder(w.raw).Encode(value); err != nil {
+	w.WriteHeader(statusCode)
+	if err := xml.NewEncoder(w).Encode(value); err != nil {
 		panic(err)
 	}
 }
+
+func (w *responseWriter) Problem(p *Problem) {
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	w.raw.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		panic(err)
+	}
+}
+
+type headResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	size       int
+}
+
+func (w *headResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	w.size += len(b)
+	return len(b), nil
+}
+
+func (w *headResponseWriter) flush() {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Length") == "" && w.size > 0 {
+		header.Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.statusCode)
+}
@@ -0,0 +1,188 @@
+package deer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	writeHeaders int
+}
+
+func (w *countingWriter) WriteHeader(statusCode int) {
+	w.writeHeaders++
+	w.ResponseRecorder.WriteHeader(statusCode)
+}
+
+func TestResponseWriterState(t *testing.T) {
+	raw := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	w := WrapResponseWriter(raw)
+	if w.Written() || w.Status() != 0 || w.Size() != 0 {
+		t.Fatalf("fresh writer: written %v status %d size %d", w.Written(), w.Status(), w.Size())
+	}
+	w.Text(http.StatusCreated, "hello")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte("!"))
+	if !w.Written() || w.Status() != http.StatusCreated || w.Size() != 6 {
+		t.Errorf("got written %v status %d size %d", w.Written(), w.Status(), w.Size())
+	}
+	if raw.writeHeaders != 1 || raw.Code != http.StatusCreated || raw.Body.String() != "hello!" {
+		t.Errorf("raw: got %d write headers, %d %q", raw.writeHeaders, raw.Code, raw.Body.String())
+	}
+}
+
+func TestResponseWriterRecoveryAfterWrite(t *testing.T) {
+	router := NewRouter().Use(Recovery())
+	router.Get("/", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusOK, "partial")
+		panic("boom")
+	})
+	raw := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(raw, httptest.NewRequest(http.MethodGet, "/", nil))
+	if raw.writeHeaders != 1 || raw.Code != http.StatusOK || raw.Body.String() != "partial" {
+		t.Errorf("got %d write headers, %d %q", raw.writeHeaders, raw.Code, raw.Body.String())
+	}
+}
+
+type hijackRecorder struct {
+	*countingWriter
+	hijacked bool
+	pushed   []string
+}
+
+func (w *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	server, client := net.Pipe()
+	client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func (w *hijackRecorder) Push(target string, opts *http.PushOptions) error {
+	w.pushed = append(w.pushed, target)
+	return nil
+}
+
+func TestResponseWriterFeatures(t *testing.T) {
+	type features struct {
+		flusher, hijacker, pusher bool
+	}
+	var got features
+	probe := func(w http.ResponseWriter) {
+		_, got.flusher = w.(http.Flusher)
+		_, got.hijacker = w.(http.Hijacker)
+		_, got.pusher = w.(http.Pusher)
+		w.Write([]byte(strings.Repeat("x", 2048)))
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	handler := func(w ResponseWriter, r *Request) { probe(w) }
+	router := NewRouter()
+	router.Get("/plain", handler)
+	router.Get("/compress", Compress()(handler))
+	router.Get("/timeout", Timeout(time.Second)(handler))
+	router.Mount("/mount", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { probe(w) }))
+	tests := []struct {
+		method string
+		path   string
+		full   bool
+		want   features
+	}{
+		{http.MethodGet, "/plain", false, features{flusher: true}},
+		{http.MethodGet, "/plain", true, features{true, true, true}},
+		{http.MethodGet, "/compress", false, features{flusher: true}},
+		{http.MethodGet, "/compress", true, features{true, true, true}},
+		{http.MethodGet, "/mount/x", false, features{flusher: true}},
+		{http.MethodGet, "/mount/x", true, features{true, true, true}},
+		{http.MethodHead, "/plain", true, features{}},
+		{http.MethodGet, "/timeout", true, features{}},
+	}
+	for _, tt := range tests {
+		got = features{}
+		rec := httptest.NewRecorder()
+		var raw http.ResponseWriter = rec
+		if tt.full {
+			raw = &hijackRecorder{countingWriter: &countingWriter{ResponseRecorder: rec}}
+		}
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		router.ServeHTTP(raw, req)
+		if got != tt.want {
+			t.Errorf("%s %s full %v: got %+v, want %+v", tt.method, tt.path, tt.full, got, tt.want)
+		}
+		if rec.Flushed != tt.want.flusher {
+			t.Errorf("%s %s full %v: got flushed %v", tt.method, tt.path, tt.full, rec.Flushed)
+		}
+	}
+}
+
+func TestResponseWriterPush(t *testing.T) {
+	raw := &hijackRecorder{countingWriter: &countingWriter{ResponseRecorder: httptest.NewRecorder()}}
+	router := NewRouter()
+	router.Get("/", func(w ResponseWriter, r *Request) {
+		if err := w.(http.Pusher).Push("/app.js", nil); err != nil {
+			t.Errorf("push: %v", err)
+		}
+	})
+	router.ServeHTTP(raw, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(raw.pushed) != 1 || raw.pushed[0] != "/app.js" {
+		t.Errorf("got pushed %v", raw.pushed)
+	}
+}
+
+func TestResponseWriterHijackMarksWritten(t *testing.T) {
+	raw := &hijackRecorder{countingWriter: &countingWriter{ResponseRecorder: httptest.NewRecorder()}}
+	router := NewRouter().Use(Recovery())
+	router.Get("/", func(w ResponseWriter, r *Request) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Fatalf("hijack: %v", err)
+		}
+		conn.Close()
+		if !w.Written() || w.Status() != http.StatusSwitchingProtocols {
+			t.Errorf("got written %v status %d", w.Written(), w.Status())
+		}
+		panic("after hijack")
+	})
+	router.ServeHTTP(raw, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !raw.hijacked || raw.writeHeaders != 0 || raw.Body.Len() != 0 {
+		t.Errorf("got hijacked %v, %d write headers, body %q", raw.hijacked, raw.writeHeaders, raw.Body.String())
+	}
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	router := NewRouter()
+	router.Get("/", func(w ResponseWriter, r *Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		rw.Flush()
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "hijacked" {
+		t.Errorf("got %q", body)
+	}
+
+	if _, _, err := http.NewResponseController(WrapResponseWriter(httptest.NewRecorder())).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("recorder hijack: got %v", err)
+	}
+}
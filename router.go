@@ -5,143 +5,484 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"regexp"
+	"net/url"
+	"path"
+	"reflect"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // region router
 
 type (
 	Router struct {
-		prefix          string
-		entryMap        map[key]*entry
-		entries         []*entry
-		middlewares     []Middleware
-		notFoundHandler HandlerFunc
+		mu                      sync.Mutex
+		frozen                  int32
+		routes                  atomic.Value
+		last                    *entry
+		middlewares             []Middleware
+		notFoundHandler         HandlerFunc
+		methodNotAllowedHandler HandlerFunc
+		errorHandler            func(w ResponseWriter, r *Request, err error)
+		problemDetails          bool
+		validator               Validator
 	}
-	key struct {
-		method string
-		path   string
+	routeTable struct {
+		prefix                string
+		redirectTrailingSlash bool
+		redirectFixedPath     bool
+		looseSlash            bool
+		trees                 table
+		hosts                 *node
+		hostTables            map[string]table
+		entries               []*entry
+		names                 map[string]*entry
+		shapes                map[string]*entry
+		hostShapes            map[string]string
+		chains                map[*entry]HandlerFunc
+		notFound              *entry
+		methodNotAllowed      *entry
+		options               *entry
+		redirect              *entry
 	}
+	table map[string]*node
 	entry struct {
+		name        string
+		host        string
 		method      string
 		pattern     string
 		handler     HandlerFunc
 		middlewares []Middleware
-		regexp      *regexp.Regexp
+		mount       http.Handler
+		source      string
 	}
 )
 
-func (e *entry) regexpMatch(path string) bool {
-	if e.regexp == nil {
-		e.regexp = regexp.MustCompile(parsePatternToRegexp(e.pattern))
-	}
-	return e.regexp.MatchString(path)
-}
-
-func (e *entry) params(path string) map[string]string {
-	if e.regexp == nil {
-		e.regexp = regexp.MustCompile(parsePatternToRegexp(e.pattern))
-	}
-	matches := e.regexp.FindStringSubmatch(path)
-	names := e.regexp.SubexpNames()
-	result := map[string]string{}
-	for i, name := range names {
-		if i > 0 {
-			result[name] = matches[i]
-		}
-	}
-	return result
-}
+const mountParamKey = "deer.mount"
 
 func NewRouter() *Router {
 	return &Router{}
 }
 
 func (router *Router) Prefix(p string) *Router {
-	router.prefix = normalizePrefix(p)
+	router.lock("prefix")
+	defer router.mu.Unlock()
+	router.mutableRoutes().prefix = normalizePrefix(p)
 	return router
 }
 
 func (router *Router) HandleNotFound(h HandlerFunc) *Router {
+	router.lock("handle not found")
+	defer router.mu.Unlock()
 	router.notFoundHandler = h
 	return router
 }
 
+func (router *Router) HandleMethodNotAllowed(h HandlerFunc) *Router {
+	router.lock("handle method not allowed")
+	defer router.mu.Unlock()
+	router.methodNotAllowedHandler = h
+	return router
+}
+
+func (router *Router) HandleError(f func(w ResponseWriter, r *Request, err error)) *Router {
+	router.lock("handle error")
+	defer router.mu.Unlock()
+	router.errorHandler = f
+	return router
+}
+
+func (router *Router) ProblemDetails(b bool) *Router {
+	router.lock("problem details")
+	defer router.mu.Unlock()
+	router.problemDetails = b
+	return router
+}
+
+func (router *Router) Validator(v Validator) *Router {
+	router.lock("validator")
+	defer router.mu.Unlock()
+	router.validator = v
+	return router
+}
+
+func (router *Router) RedirectTrailingSlash(b bool) *Router {
+	router.lock("redirect trailing slash")
+	defer router.mu.Unlock()
+	router.mutableRoutes().redirectTrailingSlash = b
+	return router
+}
+
+func (router *Router) RedirectFixedPath(b bool) *Router {
+	router.lock("redirect fixed path")
+	defer router.mu.Unlock()
+	router.mutableRoutes().redirectFixedPath = b
+	return router
+}
+
+func (router *Router) StrictSlash(b bool) *Router {
+	router.lock("strict slash")
+	defer router.mu.Unlock()
+	router.mutableRoutes().looseSlash = !b
+	return router
+}
+
 func (router *Router) Use(middlewares ...Middleware) *Router {
+	router.lock("use")
+	defer router.mu.Unlock()
 	router.middlewares = append(router.middlewares, middlewares...)
 	return router
 }
 
 func (router *Router) Handle(method string, path string, handler HandlerFunc, middlewares ...Middleware) *Router {
-	router.handle(method, path, handler, middlewares...)
+	router.handle("", method, path, handler, middlewares...)
+	return router
+}
+
+// TryHandle is like Handle but returns an error instead of panicking. Only
+// patterns that differ in param names alone conflict, such as /users/:id and
+// /users/:name. Other overlapping patterns are allowed and resolve by
+// precedence: static segments beat params and params beat wildcards, so
+// /files/new wins over /files/:name, which wins over /files/*path.
+func (router *Router) TryHandle(method string, path string, handler HandlerFunc, middlewares ...Middleware) error {
+	return router.tryHandle("", method, path, handler, middlewares...)
+}
+
+func (router *Router) handle(host string, method string, path string, handler HandlerFunc, middlewares ...Middleware) *Router {
+	if err := router.tryHandle(host, method, path, handler, middlewares...); err != nil {
+		panic(err)
+	}
 	return router
 }
 
-func (router *Router) handle(method string, path string, handler HandlerFunc, middlewares ...Middleware) *Router {
+func (router *Router) tryHandle(host string, method string, path string, handler HandlerFunc, middlewares ...Middleware) error {
 	path = normalizePath(path)
+	if handler == nil {
+		return newError("router", "handle %s %s: require handler", method, path)
+	}
 	e := entry{
+		host:        normalizeHost(host),
 		method:      method,
 		pattern:     path,
 		handler:     handler,
 		middlewares: middlewares,
+		source:      callerSource(),
+	}
+	return router.register(&e, path)
+}
+
+func (router *Router) Mount(prefix string, h http.Handler, middlewares ...Middleware) *Router {
+	return router.mount("", prefix, h, middlewares...)
+}
+
+func (router *Router) mount(host string, prefix string, h http.Handler, middlewares ...Middleware) *Router {
+	if h == nil {
+		panic(newError("router", "mount %q: require handler", prefix))
+	}
+	prefix = normalizePrefix(prefix)
+	e := entry{
+		host:    normalizeHost(host),
+		pattern: prefix,
+		handler: func(w ResponseWriter, r *Request) {
+			h.ServeHTTP(w, stripPath(r.Raw, "/"+mountRest(r.Raw)))
+		},
+		middlewares: middlewares,
+		mount:       h,
+		source:      callerSource(),
+	}
+	if err := router.register(&e, prefix, prefix+"/*"+mountParamKey); err != nil {
+		panic(err)
+	}
+	return router
+}
+
+func (router *Router) register(e *entry, patterns ...string) error {
+	if err := router.tryLock("register " + e.pattern); err != nil {
+		return err
+	}
+	defer router.mu.Unlock()
+	rt := router.mutableRoutes()
+	var hostShape string
+	if e.host != "" {
+		var err error
+		if hostShape, err = patternShape(e.host, '.'); err != nil {
+			return newError("router", "invalid host %q: %s", e.host, err)
+		}
+		if other, ok := rt.hostShapes[hostShape]; ok && other != e.host {
+			return newError("router", "host %q conflicts with host %q", e.host, other)
+		}
+	}
+	keys := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		shape, err := patternShape(pattern, '/')
+		if err != nil {
+			return newError("router", "invalid pattern %q: %s", pattern, err)
+		}
+		keys[i] = e.host + " " + e.method + " " + shape
+		if other, ok := rt.shapes[keys[i]]; ok {
+			return newError("router", "%s (%s) conflicts with %s (%s): patterns differing only in param names are ambiguous; other overlaps resolve static > param > wildcard", describeEntry(e), e.source, describeEntry(other), other.source)
+		}
 	}
-	k := key{
-		method: method,
-		path:   path,
+	if rt.shapes == nil {
+		rt.shapes = map[string]*entry{}
+	}
+	if e.host != "" {
+		if rt.hostShapes == nil {
+			rt.hostShapes = map[string]string{}
+		}
+		rt.hostShapes[hostShape] = e.host
+	}
+	t := rt.table(e.host)
+	for i, pattern := range patterns {
+		t.insert(e.method, pattern, e)
+		rt.shapes[keys[i]] = e
+	}
+	rt.entries = append(rt.entries, e)
+	router.last = e
+	return nil
+}
+
+func (router *Router) Freeze() *Router {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	if atomic.LoadInt32(&router.frozen) == 1 {
+		return router
 	}
-	if router.entryMap == nil {
-		router.entryMap = map[key]*entry{}
+	router.routes.Store(router.compile(router.loadRoutes()))
+	atomic.StoreInt32(&router.frozen, 1)
+	return router
+}
+
+// Replace atomically swaps the routes served by router for those of next.
+// next is frozen and served with its own prefix, middlewares, handlers and
+// options; the configuration of router itself no longer applies.
+func (router *Router) Replace(next *Router) *Router {
+	if next == nil {
+		panic(newError("router", "replace: require router"))
 	}
-	router.entryMap[k] = &e
-	router.entries = appendSorted(router.entries, &e)
+	next.Freeze()
+	router.Freeze()
+	router.routes.Store(next.loadRoutes())
 	return router
 }
 
+func (router *Router) compile(rt *routeTable) *routeTable {
+	compiled := *rt
+	compiled.chains = make(map[*entry]HandlerFunc, len(rt.entries)+4)
+	for _, e := range rt.entries {
+		compiled.chains[e] = router.compose(e)
+	}
+	compiled.notFound = &entry{handler: router.notFoundHandler}
+	if compiled.notFound.handler == nil {
+		compiled.notFound.handler = defaultNotFoundHandleFunc
+	}
+	compiled.methodNotAllowed = &entry{handler: router.methodNotAllowedHandler}
+	if compiled.methodNotAllowed.handler == nil {
+		compiled.methodNotAllowed.handler = defaultMethodNotAllowedHandleFunc
+	}
+	compiled.options = &entry{method: http.MethodOptions, handler: defaultOptionsHandleFunc}
+	compiled.redirect = &entry{handler: redirectHandleFunc}
+	for _, e := range []*entry{compiled.notFound, compiled.methodNotAllowed, compiled.options, compiled.redirect} {
+		compiled.chains[e] = router.compose(e)
+	}
+	return &compiled
+}
+
+func (router *Router) compose(e *entry) HandlerFunc {
+	finalMiddlewares := append([]Middleware{}, router.middlewares...)
+	finalMiddlewares = append(finalMiddlewares, e.middlewares...)
+	h := chain(e.handler, finalMiddlewares...)
+	errorHandler := router.errorHandler
+	if errorHandler == nil {
+		errorHandler = defaultErrorHandleFunc
+	}
+	problemDetails := router.problemDetails
+	validator := router.validator
+	return func(w ResponseWriter, r *Request) {
+		r.errorHandler = errorHandler
+		r.problemDetails = problemDetails
+		r.validator = validator
+		h(w, r)
+	}
+}
+
+func (router *Router) lock(op string) {
+	if err := router.tryLock(op); err != nil {
+		panic(err)
+	}
+}
+
+func (router *Router) tryLock(op string) error {
+	router.mu.Lock()
+	if atomic.LoadInt32(&router.frozen) == 1 {
+		router.mu.Unlock()
+		return newError("router", "%s: router is frozen", op)
+	}
+	return nil
+}
+
+func (router *Router) loadRoutes() *routeTable {
+	if rt, ok := router.routes.Load().(*routeTable); ok {
+		return rt
+	}
+	return &routeTable{}
+}
+
+func (router *Router) mutableRoutes() *routeTable {
+	rt, ok := router.routes.Load().(*routeTable)
+	if !ok {
+		rt = &routeTable{}
+		router.routes.Store(rt)
+	}
+	return rt
+}
+
+func (rt *routeTable) table(host string) table {
+	if host == "" {
+		if rt.trees == nil {
+			rt.trees = table{}
+		}
+		return rt.trees
+	}
+	if rt.hosts == nil {
+		rt.hosts = newTree('.')
+		rt.hostTables = map[string]table{}
+	}
+	t := rt.hostTables[host]
+	if t == nil {
+		t = table{}
+		rt.hostTables[host] = t
+		rt.hosts.insert(host, &entry{pattern: host})
+	}
+	return t
+}
+
+// tables returns the host's table, if any, followed by the default one. Host
+// params are appended to params.
+func (rt *routeTable) tables(host string, params []param) ([2]table, []param) {
+	if rt.hosts == nil {
+		return [2]table{rt.trees}, params
+	}
+	e, ps := rt.hosts.lookup(strings.ToLower(stripPort(host)), params)
+	if e == nil {
+		return [2]table{rt.trees}, params
+	}
+	return [2]table{rt.hostTables[e.pattern], rt.trees}, ps
+}
+
+func (rt *routeTable) redirectPath(t table, method string, path string) (string, bool) {
+	if rt.redirectTrailingSlash {
+		if alt := toggleTrailingSlash(path); alt != "" {
+			if e, _ := t.find(method, alt, nil); e != nil {
+				return alt, true
+			}
+		}
+	}
+	if rt.redirectFixedPath {
+		cleaned := cleanPath(path)
+		if fixed, ok := t.findFold(method, cleaned); ok {
+			return fixed, true
+		}
+		if rt.redirectTrailingSlash {
+			if alt := toggleTrailingSlash(cleaned); alt != "" {
+				if fixed, ok := t.findFold(method, alt); ok {
+					return fixed, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
 func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&router.frozen) == 0 {
+		router.Freeze()
+	}
+	r, st := withState(r)
 	method, path := r.Method, r.URL.Path
+	rt := router.loadRoutes()
+	tables, hostParams := rt.tables(r.Host, st.params)
 	var e *entry
-	if strings.HasPrefix(path, router.prefix) {
-		path = strings.TrimPrefix(path, router.prefix)
-		k := key{
-			method: method,
-			path:   path,
+	matched := strings.HasPrefix(path, rt.prefix)
+	if matched {
+		path = strings.TrimPrefix(path, rt.prefix)
+		var params []param
+		for _, t := range tables {
+			if t == nil {
+				continue
+			}
+			e, params = t.find(method, path, hostParams)
+			if e == nil && rt.looseSlash {
+				if alt := toggleTrailingSlash(path); alt != "" {
+					e, params = t.find(method, alt, hostParams)
+				}
+			}
+			if e != nil {
+				break
+			}
 		}
-		if router.entryMap == nil {
-			router.entryMap = map[key]*entry{}
+		var rest string
+		if e != nil && e.mount != nil {
+			if n := len(params); n > 0 && params[n-1].key == mountParamKey {
+				rest, params = params[n-1].value, params[:n-1]
+			}
 		}
-		e = router.entryMap[k]
-		if e == nil {
-			regexpMatch := false
-			for _, v := range router.entries {
-				if v.regexpMatch(path) && v.method == method {
-					regexpMatch = true
-					e = v
-					break
-				}
+		if e != nil {
+			st.setRoute(rt.prefix+e.pattern, e.mount != nil, rest, params)
+		}
+	}
+	if e == nil && matched && method != http.MethodConnect {
+		for _, t := range tables {
+			if t == nil {
+				continue
 			}
-			if regexpMatch && e != nil {
-				r = r.WithContext(context.WithValue(r.Context(), paramsContextKeySingleton, e.params(path)))
+			if target, ok := rt.redirectPath(t, method, path); ok && isLocalRedirect(rt.prefix+target) {
+				st.mu.Lock()
+				st.redirect = rt.prefix + target
+				st.mu.Unlock()
+				e = rt.redirect
+				break
 			}
 		}
 	}
-	if e == nil {
-		if method == http.MethodOptions {
-			e = &entry{
-				method:  http.MethodOptions,
-				handler: defaultOptionsHandleFunc,
+	var allowed []string
+	if matched && (e == nil || method == http.MethodOptions) {
+		for _, t := range tables {
+			if t == nil {
+				continue
 			}
-		} else {
-			e = &entry{
-				method:  method,
-				handler: defaultNotFoundHandleFunc,
+			if allowed = t.allowedMethods(path); len(allowed) > 0 {
+				break
 			}
 		}
+		if method == http.MethodOptions && len(allowed) > 0 {
+			st.mu.Lock()
+			st.allowed = allowed
+			st.mu.Unlock()
+		}
+	}
+	if e == nil {
+		switch {
+		case len(allowed) == 0:
+			e = rt.notFound
+		case method == http.MethodOptions:
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			e = rt.options
+		default:
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			e = rt.methodNotAllowed
+		}
+	}
+	h := rt.chains[e]
+	if method == http.MethodHead && e.method == http.MethodGet {
+		hw := &headResponseWriter{ResponseWriter: w}
+		h.ServeHTTP(hw, r)
+		hw.flush()
+		return
 	}
-	finalMiddlewares := append([]Middleware{}, router.middlewares...)
-	finalMiddlewares = append(finalMiddlewares, e.middlewares...)
-	h := chain(e.handler, finalMiddlewares...)
 	h.ServeHTTP(w, r)
 }
 
@@ -149,6 +490,10 @@ func (router *Router) Group(prefix string, middlewares ...Middleware) *group {
 	return &group{router: router, prefix: normalizePrefix(prefix), middlewares: middlewares}
 }
 
+func (router *Router) Host(pattern string, middlewares ...Middleware) *group {
+	return &group{router: router, host: pattern, middlewares: middlewares}
+}
+
 func (router *Router) Any(pattern string, handler HandlerFunc, middlewares ...Middleware) *Router {
 	router.Handle(http.MethodGet, pattern, handler, middlewares...)
 	router.Handle(http.MethodPost, pattern, handler, middlewares...)
@@ -188,7 +533,79 @@ func (router *Router) Options(pattern string, handler HandlerFunc, middlewares .
 	return router.Handle(http.MethodOptions, pattern, handler, middlewares...)
 }
 
+func (router *Router) HandleE(method string, path string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Handle(method, path, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) AnyE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Any(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) GetE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Get(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) PostE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Post(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) PutE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Put(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) PatchE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Patch(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) DeleteE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Delete(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) OptionsE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *Router {
+	return router.Options(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (router *Router) Name(name string) *Router {
+	router.lock("name")
+	defer router.mu.Unlock()
+	if router.last == nil {
+		panic(newError("router", "name %q: no route registered", name))
+	}
+	rt := router.mutableRoutes()
+	if rt.names == nil {
+		rt.names = map[string]*entry{}
+	}
+	if e, ok := rt.names[name]; ok && e.pattern != router.last.pattern {
+		panic(newError("router", "name %q already used by pattern %q", name, e.pattern))
+	}
+	router.last.name = name
+	rt.names[name] = router.last
+	return router
+}
+
+func (router *Router) URL(name string, pairs ...string) (string, error) {
+	rt := router.loadRoutes()
+	e, ok := rt.names[name]
+	if !ok {
+		return "", newError("router", "url: unknown route name %q", name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", newError("router", "url: odd number of param pairs for route %q", name)
+	}
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+	p, err := buildPath(e.pattern, values)
+	if err != nil {
+		return "", newError("router", "url: route %q: %s", name, err)
+	}
+	return rt.prefix + p, nil
+}
+
 type EntryView struct {
+	Name    string `json:"name,omitempty"`
+	Host    string `json:"host,omitempty"`
 	Method  string `json:"method"`
 	Pattern string `json:"pattern"`
 }
@@ -206,15 +623,36 @@ var methodOrders = map[string]int{
 	http.MethodTrace:   9,
 }
 
+func methodLess(a string, b string) bool {
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	oa, oka := methodOrders[a]
+	ob, okb := methodOrders[b]
+	if oka && okb {
+		return oa < ob
+	}
+	if oka != okb {
+		return oka
+	}
+	return a < b
+}
+
+func sortMethods(methods []string) {
+	sort.Slice(methods, func(i, j int) bool {
+		return methodLess(methods[i], methods[j])
+	})
+}
+
 func (router *Router) Items() []EntryView {
-	items := append([]*entry{}, router.entries...)
+	rt := router.loadRoutes()
+	items := append([]*entry{}, rt.entries...)
 	sort.Slice(items, func(i, j int) bool {
+		if items[i].host != items[j].host {
+			return items[i].host < items[j].host
+		}
 		if items[i].pattern != items[j].pattern {
 			return items[i].pattern < items[j].pattern
 		}
-		mi := strings.ToUpper(items[i].method)
-		mj := strings.ToUpper(items[j].method)
-		return methodOrders[mi] < methodOrders[mj]
+		return methodLess(items[i].method, items[j].method)
 	})
 	var result []EntryView
 	for _, item := range items {
@@ -222,8 +660,23 @@ func (router *Router) Items() []EntryView {
 		if method == "" {
 			method = "ANY"
 		}
-		pattern = router.prefix + pattern
+		pattern = rt.prefix + pattern
+		if item.mount != nil {
+			if child, ok := item.mount.(*Router); ok {
+				for _, v := range child.Items() {
+					if v.Host == "" {
+						v.Host = item.host
+					}
+					v.Pattern = pattern + v.Pattern
+					result = append(result, v)
+				}
+				continue
+			}
+			pattern += "/*"
+		}
 		result = append(result, EntryView{
+			Name:    item.name,
+			Host:    item.host,
 			Method:  method,
 			Pattern: pattern,
 		})
@@ -235,7 +688,7 @@ func (router *Router) String() string {
 	builder := bytes.Buffer{}
 	items := router.Items()
 	for _, item := range items {
-		builder.WriteString(fmt.Sprintf("%-7s %s\n", item.Method, item.Pattern))
+		builder.WriteString(fmt.Sprintf("%-7s %s%s\n", item.Method, item.Host, item.Pattern))
 	}
 	return strings.TrimSuffix(builder.String(), "\n")
 }
@@ -244,12 +697,81 @@ func (router *Router) Run(addr string) error {
 	return http.ListenAndServe(addr, router)
 }
 
+func (t table) insert(method string, path string, e *entry) {
+	root := t[method]
+	if root == nil {
+		root = newTree('/')
+		t[method] = root
+	}
+	root.insert(path, e)
+}
+
+func (t table) lookup(method string, path string, params []param) (*entry, []param) {
+	root := t[method]
+	if root == nil {
+		return nil, params
+	}
+	return root.lookup(path, params)
+}
+
+// find appends the matched params to params, reusing its spare capacity.
+func (t table) find(method string, path string, params []param) (*entry, []param) {
+	e, ps := t.lookup(method, path, params)
+	if e == nil && method == http.MethodHead {
+		e, ps = t.lookup(http.MethodGet, path, params)
+	}
+	if e == nil {
+		e, ps = t.lookup("", path, params)
+	}
+	return e, ps
+}
+
+func (t table) findFold(method string, path string) (string, bool) {
+	methods := []string{method}
+	if method == http.MethodHead {
+		methods = append(methods, http.MethodGet)
+	}
+	methods = append(methods, "")
+	for _, m := range methods {
+		if root := t[m]; root != nil {
+			if fixed, ok := root.lookupFold(path, nil); ok {
+				return string(fixed), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (t table) allowedMethods(path string) []string {
+	var result []string
+	for method, root := range t {
+		if method == "" {
+			continue
+		}
+		if e, _ := root.lookup(path, nil); e != nil {
+			result = append(result, method)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	if containsString(result, http.MethodGet) && !containsString(result, http.MethodHead) {
+		result = append(result, http.MethodHead)
+	}
+	if !containsString(result, http.MethodOptions) {
+		result = append(result, http.MethodOptions)
+	}
+	sortMethods(result)
+	return result
+}
+
 // endregion
 
 // region router group
 
 type group struct {
 	router      *Router
+	host        string
 	prefix      string
 	middlewares []Middleware
 }
@@ -262,12 +784,24 @@ func (g *group) Handle(method string, path string, handler HandlerFunc, middlewa
 	path = g.prefix + path
 	finalMiddlewares := append([]Middleware{}, g.middlewares...)
 	finalMiddlewares = append(finalMiddlewares, middlewares...)
-	g.router.Handle(method, path, handler, finalMiddlewares...)
+	g.router.handle(g.host, method, path, handler, finalMiddlewares...)
+	return g
+}
+
+func (g *group) Mount(prefix string, h http.Handler, middlewares ...Middleware) *group {
+	finalMiddlewares := append([]Middleware{}, g.middlewares...)
+	finalMiddlewares = append(finalMiddlewares, middlewares...)
+	g.router.mount(g.host, g.prefix+normalizePrefix(prefix), h, finalMiddlewares...)
 	return g
 }
 
 func (g *group) Group(prefix string) *group {
-	return &group{router: g.router, prefix: g.prefix + normalizePrefix(prefix)}
+	return &group{router: g.router, host: g.host, prefix: g.prefix + normalizePrefix(prefix)}
+}
+
+func (g *group) Name(name string) *group {
+	g.router.Name(name)
+	return g
 }
 
 func (g *group) Use(middlewares ...Middleware) *group {
@@ -314,44 +848,193 @@ func (g *group) Options(pattern string, handler HandlerFunc, middlewares ...Midd
 	return g.Handle(http.MethodOptions, pattern, handler, middlewares...)
 }
 
+func (g *group) HandleE(method string, path string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Handle(method, path, handler.handlerFunc(), middlewares...)
+}
+
+func (g *group) AnyE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Any(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (g *group) GetE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Get(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (g *group) PostE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Post(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (g *group) PutE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Put(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (g *group) PatchE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Patch(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (g *group) DeleteE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Delete(pattern, handler.handlerFunc(), middlewares...)
+}
+
+func (g *group) OptionsE(pattern string, handler ErrorHandlerFunc, middlewares ...Middleware) *group {
+	return g.Options(pattern, handler.handlerFunc(), middlewares...)
+}
+
 // endregion
 
 // region params
 
-type paramsContextKey struct{}
+type stateContextKey struct{}
+
+var stateContextKeySingleton = stateContextKey{}
+
+// requestState is everything the router attaches to a request: the values
+// from Set, the matched route and its params. It is allocated once per
+// request together with its context and shared by mounted routers and
+// Timeout goroutines, so mu guards its fields.
+type requestState struct {
+	mu       sync.Mutex
+	values   map[string]interface{}
+	pattern  string
+	mount    bool
+	rest     string
+	params   []param
+	buf      [4]param
+	allowed  []string
+	redirect string
+}
+
+type stateContext struct {
+	context.Context
+	state requestState
+}
+
+func (ctx *stateContext) Value(key interface{}) interface{} {
+	if key == stateContextKeySingleton {
+		return &ctx.state
+	}
+	return ctx.Context.Value(key)
+}
+
+func stateFromContext(ctx context.Context) *requestState {
+	s, _ := ctx.Value(stateContextKeySingleton).(*requestState)
+	return s
+}
+
+func withState(r *http.Request) (*http.Request, *requestState) {
+	if s := stateFromContext(r.Context()); s != nil {
+		return r, s
+	}
+	ctx := &stateContext{Context: r.Context()}
+	ctx.state.params = ctx.state.buf[:0]
+	return r.WithContext(ctx), &ctx.state
+}
 
-var paramsContextKeySingleton = paramsContextKey{}
+// setRoute records a match. Inside a mount the pattern extends the mount's
+// and the params add to its params, which they already extend in place.
+func (s *requestState) setRoute(pattern string, mount bool, rest string, params []param) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mount {
+		pattern = s.pattern + pattern
+	}
+	s.pattern, s.mount, s.rest, s.params = pattern, mount, rest, params
+}
+
+func (s *requestState) route() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pattern, s.rest
+}
+
+// param finds the latest param named key, so a mounted router's params
+// shadow the parent's.
+func (s *requestState) param(key string) (param, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.params) - 1; i >= 0; i-- {
+		if s.params[i].key == key {
+			return s.params[i], true
+		}
+	}
+	return param{}, false
+}
 
 func Params(r *http.Request) map[string]string {
-	m, ok := r.Context().Value(paramsContextKeySingleton).(map[string]string)
-	if ok {
-		return m
+	m := map[string]string{}
+	if s := stateFromContext(r.Context()); s != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, p := range s.params {
+			m[p.key] = p.value
+		}
+	}
+	return m
+}
+
+func allowedMethodsFromContext(ctx context.Context) []string {
+	s := stateFromContext(ctx)
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.allowed
+}
+
+func Route(r *http.Request) string {
+	if s := stateFromContext(r.Context()); s != nil {
+		pattern, _ := s.route()
+		return pattern
 	}
-	return map[string]string{}
+	return ""
+}
+
+func mountRest(r *http.Request) string {
+	if s := stateFromContext(r.Context()); s != nil {
+		_, rest := s.route()
+		return rest
+	}
+	return ""
 }
 
 // endregion
 
 // region utils
 
-var (
-	namedParamRegexp    = regexp.MustCompile(":([^/]+)")
-	wildcardParamRegexp = regexp.MustCompile("\\*([^/]+)")
-)
+func defaultOptionsHandleFunc(w ResponseWriter, r *Request) {
+	w.SetHeader("Content-Length", "0")
+	w.StatusCode(http.StatusNoContent)
+}
 
-func parsePatternToRegexp(pattern string) string {
-	s := namedParamRegexp.ReplaceAllString(pattern, "(?P<$1>[^/]+)")
-	s = wildcardParamRegexp.ReplaceAllString(s, "(?P<$1>.*)")
-	s = "^" + s + "$"
-	return s
+func defaultMethodNotAllowedHandleFunc(w ResponseWriter, r *Request) {
+	if r.problemDetails {
+		w.Problem(NewProblem(http.StatusMethodNotAllowed))
+		return
+	}
+	w.Text(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
 }
 
-func defaultOptionsHandleFunc(w ResponseWriter, r *Request) {
-	w.Header("Content-Length", "0")
-	w.StatusCode(http.StatusNoContent)
+func redirectHandleFunc(w ResponseWriter, r *Request) {
+	s := r.state()
+	s.mu.Lock()
+	target := s.redirect
+	s.mu.Unlock()
+	statusCode := http.StatusPermanentRedirect
+	if r.Method() == http.MethodGet || r.Method() == http.MethodHead {
+		statusCode = http.StatusMovedPermanently
+	}
+	if r.Raw.URL.RawQuery != "" {
+		target += "?" + r.Raw.URL.RawQuery
+	}
+	http.Redirect(w, r.Raw, target, statusCode)
 }
 
 func defaultNotFoundHandleFunc(w ResponseWriter, r *Request) {
+	if r.problemDetails {
+		w.Problem(NewProblem(http.StatusNotFound))
+		return
+	}
 	w.Text(http.StatusNotFound, http.StatusText(http.StatusNotFound))
 }
 
@@ -365,6 +1048,145 @@ func normalizePath(p string) string {
 	return p
 }
 
+func buildPath(pattern string, values map[string]string) (string, error) {
+	builder := strings.Builder{}
+	for pattern != "" {
+		switch pattern[0] {
+		case ':':
+			name, spec, rest, err := splitParam(pattern[1:], '/')
+			if err != nil {
+				return "", err
+			}
+			value, ok := values[name]
+			if !ok || value == "" {
+				return "", fmt.Errorf("missing param %q", name)
+			}
+			if spec != "" {
+				c, err := parseConstraint(spec)
+				if err != nil {
+					return "", err
+				}
+				if _, ok := c.parse(value); !ok {
+					return "", fmt.Errorf("param %q value %q violates constraint %q", name, value, spec)
+				}
+			}
+			builder.WriteString(url.PathEscape(value))
+			pattern = rest
+		case '*':
+			name := pattern[1:]
+			value, ok := values[name]
+			if !ok {
+				return "", fmt.Errorf("missing param %q", name)
+			}
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			builder.WriteString(strings.Join(segments, "/"))
+			pattern = ""
+		default:
+			end := strings.IndexAny(pattern, ":*")
+			if end < 0 {
+				end = len(pattern)
+			}
+			builder.WriteString(pattern[:end])
+			pattern = pattern[end:]
+		}
+	}
+	return builder.String(), nil
+}
+
+func stripPath(r *http.Request, p string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = p
+	r2.URL.RawPath = ""
+	if escaped := r.URL.EscapedPath(); escaped != r.URL.Path {
+		// Keep the escaped form of the rest, e.g. a%2Fb, by finding the
+		// escaped suffix that decodes to p.
+		for i := 0; i < len(escaped); i++ {
+			if escaped[i] != '/' {
+				continue
+			}
+			if s, err := url.PathUnescape(escaped[i:]); err == nil && s == p {
+				r2.URL.RawPath = escaped[i:]
+				break
+			}
+		}
+	}
+	return r2
+}
+
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	result := path.Clean(p)
+	if p[len(p)-1] == '/' && result != "/" {
+		result += "/"
+	}
+	return result
+}
+
+// isLocalRedirect rejects targets that browsers resolve to another host,
+// such as //evil.com or /\evil.com, which a param route happily matches.
+func isLocalRedirect(target string) bool {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+		return false
+	}
+	first := target[1:]
+	if i := strings.IndexByte(first, '/'); i >= 0 {
+		first = first[:i]
+	}
+	return !strings.Contains(first, "\\")
+}
+
+func toggleTrailingSlash(p string) string {
+	if p == "/" {
+		return ""
+	}
+	if strings.HasSuffix(p, "/") {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
+
+func normalizeHost(pattern string) string {
+	b := []byte(pattern)
+	inParam, depth := false, 0
+	for i, c := range b {
+		switch {
+		case depth > 0:
+			if c == '<' {
+				depth++
+			} else if c == '>' {
+				depth--
+			}
+		case inParam && c == '<':
+			depth++
+		case c == ':' || c == '*':
+			inParam = true
+		case c == '.':
+			inParam = false
+		case !inParam && 'A' <= c && c <= 'Z':
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func stripPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		return host[:i]
+	}
+	return host
+}
+
 func normalizePrefix(p string) string {
 	p = strings.TrimRight(p, "/")
 	if p == "" {
@@ -376,30 +1198,38 @@ func normalizePrefix(p string) string {
 	return p
 }
 
-func appendSorted(es []*entry, e *entry) []*entry {
-	n := len(es)
-	findIndex := sort.Search(n, func(i int) bool {
-		return es[i].method == e.method && es[i].pattern == e.pattern
-	})
-	if findIndex < n {
-		es[findIndex] = e
-		return es
-	}
-	smallestIndex := sort.Search(n, func(i int) bool {
-		l1 := len(strings.Split(es[i].pattern, "/"))
-		l2 := len(strings.Split(e.pattern, "/"))
-		if l1 != l2 {
-			return l1 < l2
+func describeEntry(e *entry) string {
+	method := e.method
+	if method == "" {
+		method = "ANY"
+	}
+	return fmt.Sprintf("%s %s%s", method, e.host, e.pattern)
+}
+
+var packagePath = reflect.TypeOf(entry{}).PkgPath()
+
+func callerSource() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePath+".") || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
 		}
-		return len(es[i].pattern) < len(e.pattern)
-	})
-	if smallestIndex == n {
-		return append(es, e)
 	}
-	es = append(es, nil)
-	copy(es[smallestIndex+1:], es[smallestIndex:])
-	es[smallestIndex] = e
-	return es
+	return false
 }
 
 func chain(h HandlerFunc, middlewares ...Middleware) HandlerFunc {
@@ -0,0 +1,581 @@
+package deer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func serve(h http.Handler, method string, target string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func echoRoute(w ResponseWriter, r *Request) {
+	w.Text(http.StatusOK, r.Route())
+}
+
+func TestRouterPriority(t *testing.T) {
+	router := NewRouter()
+	for _, pattern := range []string{
+		"/users/new",
+		"/users/:id",
+		"/users/:id/posts/:post",
+		"/a/:x/c",
+		"/a/b/d",
+		"/items/:id<int>",
+		"/items/:slug",
+		"/files/new",
+		"/files/*path",
+	} {
+		router.Get(pattern, echoRoute)
+	}
+	tests := []struct {
+		path  string
+		code  int
+		route string
+	}{
+		{"/users/new", http.StatusOK, "/users/new"},
+		{"/users/42", http.StatusOK, "/users/:id"},
+		{"/users/42/posts/7", http.StatusOK, "/users/:id/posts/:post"},
+		{"/a/b/c", http.StatusOK, "/a/:x/c"},
+		{"/a/b/d", http.StatusOK, "/a/b/d"},
+		{"/items/42", http.StatusOK, "/items/:id<int>"},
+		{"/items/abc", http.StatusOK, "/items/:slug"},
+		{"/files/new", http.StatusOK, "/files/new"},
+		{"/files/a/b", http.StatusOK, "/files/*path"},
+		{"/nope", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(router, http.MethodGet, tt.path)
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: got %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.route != "" && rec.Body.String() != tt.route {
+			t.Errorf("GET %s: got route %q, want %q", tt.path, rec.Body.String(), tt.route)
+		}
+	}
+}
+
+func TestRouterParams(t *testing.T) {
+	router := NewRouter()
+	router.Get("/users/:id/files/*path", func(w ResponseWriter, r *Request) {
+		id, err := r.ParamInt("id")
+		if err != nil {
+			t.Errorf("param int: %v", err)
+		}
+		if id != 42 || r.Param("path") != "a/b.txt" {
+			t.Errorf("got id %d path %q", id, r.Param("path"))
+		}
+		if Params(r.Raw)["id"] != "42" {
+			t.Errorf("got params %v", Params(r.Raw))
+		}
+		w.StatusCode(http.StatusNoContent)
+	})
+	if rec := serve(router, http.MethodGet, "/users/42/files/a/b.txt"); rec.Code != http.StatusNoContent {
+		t.Fatalf("got %d", rec.Code)
+	}
+}
+
+func TestRouterPrefix(t *testing.T) {
+	router := NewRouter().Prefix("/api")
+	router.Get("/users/:id", echoRoute)
+	if rec := serve(router, http.MethodGet, "/api/users/1"); rec.Code != http.StatusOK || rec.Body.String() != "/api/users/:id" {
+		t.Errorf("got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := serve(router, http.MethodGet, "/users/1"); rec.Code != http.StatusNotFound {
+		t.Errorf("got %d", rec.Code)
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	router := NewRouter()
+	router.Get("/users/:id", echoRoute)
+	router.Delete("/users/:id", echoRoute)
+	router.Post("/users", echoRoute)
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{http.MethodPut, "/users/1", http.StatusMethodNotAllowed, "GET, HEAD, DELETE, OPTIONS"},
+		{http.MethodGet, "/users", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{http.MethodGet, "/users/1", http.StatusOK, ""},
+		{http.MethodPut, "/nope", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(router, tt.method, tt.path)
+		if rec.Code != tt.code || rec.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s: got %d allow %q, want %d allow %q", tt.method, tt.path, rec.Code, rec.Header().Get("Allow"), tt.code, tt.allow)
+		}
+	}
+}
+
+func TestRouterHandleMethodNotAllowed(t *testing.T) {
+	router := NewRouter().HandleMethodNotAllowed(func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusTeapot, "custom")
+	})
+	router.Get("/a", echoRoute)
+	rec := serve(router, http.MethodPost, "/a")
+	if rec.Code != http.StatusTeapot || rec.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("got %d allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestRouterOptions(t *testing.T) {
+	router := NewRouter().Use(CORS(CORSOptions{AllowOrigins: []string{"*"}, AllowMethods: []string{"GET", "PUT", "DELETE"}}))
+	router.Get("/users/:id", echoRoute)
+	router.Put("/users/:id", echoRoute)
+	router.Options("/custom", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusOK, "custom")
+	})
+	rec := serve(router, http.MethodOptions, "/users/1")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "GET, HEAD, PUT, OPTIONS" {
+		t.Errorf("options: got %d allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	rec = serve(router, http.MethodOptions, "/users/1", "Origin", "http://example.com", "Access-Control-Request-Method", "PUT")
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET,PUT" {
+		t.Errorf("preflight: got allow methods %q", got)
+	}
+	if rec := serve(router, http.MethodOptions, "/custom"); rec.Code != http.StatusOK || rec.Body.String() != "custom" {
+		t.Errorf("custom options: got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := serve(router, http.MethodOptions, "/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown options: got %d", rec.Code)
+	}
+}
+
+func TestRouterHead(t *testing.T) {
+	router := NewRouter()
+	router.Get("/text", func(w ResponseWriter, r *Request) {
+		w.SetHeader("X-Method", r.Method())
+		w.Text(http.StatusOK, "hello")
+	})
+	router.Handle(http.MethodHead, "/explicit", func(w ResponseWriter, r *Request) {
+		w.SetHeader("X-Explicit", "1")
+		w.StatusCode(http.StatusOK)
+	})
+	router.Get("/explicit", echoRoute)
+	rec := serve(router, http.MethodHead, "/text")
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "5" || rec.Header().Get("X-Method") != http.MethodHead {
+		t.Errorf("implicit head: got %d body %q headers %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+	if rec := serve(router, http.MethodHead, "/explicit"); rec.Header().Get("X-Explicit") != "1" {
+		t.Errorf("explicit head: got headers %v", rec.Header())
+	}
+}
+
+func TestRouterRedirects(t *testing.T) {
+	router := NewRouter().RedirectTrailingSlash(true).RedirectFixedPath(true)
+	router.Get("/users/:id", echoRoute)
+	router.Get("/docs/", echoRoute)
+	router.Post("/forms", echoRoute)
+	tests := []struct {
+		method   string
+		target   string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/users/1/", http.StatusMovedPermanently, "/users/1"},
+		{http.MethodGet, "/docs", http.StatusMovedPermanently, "/docs/"},
+		{http.MethodGet, "/docs?x=1", http.StatusMovedPermanently, "/docs/?x=1"},
+		{http.MethodGet, "/USERS/1", http.StatusMovedPermanently, "/users/1"},
+		{http.MethodGet, "/a/../users/1", http.StatusMovedPermanently, "/users/1"},
+		{http.MethodPost, "/forms/", http.StatusPermanentRedirect, "/forms"},
+		{http.MethodGet, "/users/1", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(router, tt.method, tt.target)
+		if rec.Code != tt.code || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: got %d location %q, want %d location %q", tt.method, tt.target, rec.Code, rec.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+}
+
+func TestRouterStrictSlash(t *testing.T) {
+	router := NewRouter().StrictSlash(false)
+	router.Get("/users", echoRoute)
+	if rec := serve(router, http.MethodGet, "/users/"); rec.Code != http.StatusOK {
+		t.Errorf("loose slash: got %d", rec.Code)
+	}
+	strict := NewRouter()
+	strict.Get("/users", echoRoute)
+	if rec := serve(strict, http.MethodGet, "/users/"); rec.Code != http.StatusNotFound {
+		t.Errorf("strict slash: got %d", rec.Code)
+	}
+}
+
+func TestRouterHost(t *testing.T) {
+	router := NewRouter()
+	router.Host(":tenantID.Example.com").Get("/users/:id", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusOK, r.Param("tenantID")+" "+r.Param("id"))
+	})
+	router.Host("api.example.com").Get("/", echoRoute)
+	router.Get("/users/:id", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusOK, "fallback "+r.Param("id"))
+	})
+	tests := []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{"acme.example.com", "/users/1", http.StatusOK, "acme 1"},
+		{"ACME.EXAMPLE.COM:8080", "/users/1", http.StatusOK, "acme 1"},
+		{"api.example.com", "/", http.StatusOK, "/"},
+		{"other.org", "/users/2", http.StatusOK, "fallback 2"},
+		{"other.org", "/", http.StatusNotFound, "Not Found"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.code || rec.Body.String() != tt.body {
+			t.Errorf("%s%s: got %d %q, want %d %q", tt.host, tt.path, rec.Code, rec.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	tests := map[string]string{
+		"API.Example.COM":             "api.example.com",
+		":tenantID.Example.com":       ":tenantID.example.com",
+		":ID<regex:[A-Z]+>.Example.a": ":ID<regex:[A-Z]+>.example.a",
+		"*Rest":                       "*Rest",
+	}
+	for in, want := range tests {
+		if got := normalizeHost(in); got != want {
+			t.Errorf("normalize %q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	router := NewRouter().Prefix("/api")
+	router.Get("/users/:id<int>", echoRoute).Name("user")
+	router.Get("/files/*path", echoRoute).Name("file")
+	tests := []struct {
+		name  string
+		pairs []string
+		url   string
+		err   bool
+	}{
+		{"user", []string{"id", "42"}, "/api/users/42", false},
+		{"user", []string{"id", "abc"}, "", true},
+		{"user", nil, "", true},
+		{"user", []string{"id"}, "", true},
+		{"file", []string{"path", "a b/c.txt"}, "/api/files/a%20b/c.txt", false},
+		{"missing", nil, "", true},
+	}
+	for _, tt := range tests {
+		u, err := router.URL(tt.name, tt.pairs...)
+		if (err != nil) != tt.err || u != tt.url {
+			t.Errorf("url %s %v: got %q %v, want %q", tt.name, tt.pairs, u, err, tt.url)
+		}
+	}
+}
+
+func TestRouterNameConflict(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic")
+		}
+	}()
+	router := NewRouter()
+	router.Get("/a", echoRoute).Name("x")
+	router.Get("/b", echoRoute).Name("x")
+}
+
+func TestRouterMount(t *testing.T) {
+	var seen []string
+	record := func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			seen = append(seen, r.Path())
+			h.Next(w, r)
+		}
+	}
+	child := NewRouter().HandleNotFound(func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusNotFound, "child not found")
+	})
+	child.Get("/invoices/:id", func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusOK, r.Param("org")+" "+r.Param("id")+" "+r.Route()+" "+r.Path())
+	})
+	router := NewRouter().Use(record)
+	router.Mount("/orgs/:org/billing", child)
+	router.Mount("/raw", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.URL.EscapedPath()))
+	}))
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/orgs/7/billing/invoices/9", http.StatusOK, "7 9 /orgs/:org/billing/invoices/:id /invoices/9"},
+		{"/orgs/7/billing/nope", http.StatusNotFound, "child not found"},
+		{"/raw/a/b", http.StatusOK, "/a/b /a/b"},
+		{"/raw/a%2Fb", http.StatusOK, "/a/b /a%2Fb"},
+		{"/raw/x%20y/a%2Fb?q=1", http.StatusOK, "/x y/a/b /x%20y/a%2Fb"},
+		{"/raw", http.StatusOK, "/ /"},
+	}
+	for _, tt := range tests {
+		seen = nil
+		rec := serve(router, http.MethodGet, tt.path)
+		if rec.Code != tt.code || rec.Body.String() != tt.body {
+			t.Errorf("GET %s: got %d %q, want %d %q", tt.path, rec.Code, rec.Body.String(), tt.code, tt.body)
+		}
+		want, _, _ := strings.Cut(tt.path, "?")
+		want, _ = url.PathUnescape(want)
+		if len(seen) != 1 || seen[0] != want {
+			t.Errorf("GET %s: parent middleware saw %v", tt.path, seen)
+		}
+	}
+	items := router.Items()
+	if len(items) != 2 || items[0].Pattern != "/orgs/:org/billing/invoices/:id" || items[1].Pattern != "/raw/*" {
+		t.Errorf("items: got %+v", items)
+	}
+}
+
+func TestRouterFreeze(t *testing.T) {
+	router := NewRouter()
+	router.Get("/a", echoRoute)
+	serve(router, http.MethodGet, "/a")
+	if err := router.TryHandle(http.MethodGet, "/b", echoRoute); err == nil {
+		t.Error("handle after freeze: want error")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("use after freeze: want panic")
+		}
+	}()
+	router.Use(Recovery())
+}
+
+func TestRouterReplace(t *testing.T) {
+	router := NewRouter().Use(func(h HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			w.SetHeader("X-Old", "1")
+			h.Next(w, r)
+		}
+	})
+	router.Get("/secret", echoRoute)
+	router.Get("/old", echoRoute)
+	if rec := serve(router, http.MethodGet, "/secret"); rec.Code != http.StatusOK {
+		t.Fatalf("before replace: got %d", rec.Code)
+	}
+
+	next := NewRouter().Prefix("/v2").Use(BasicAuth(map[string]string{"u": "p"})).HandleNotFound(func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusNotFound, "next not found")
+	})
+	next.Get("/secret", echoRoute)
+	router.Replace(next)
+
+	rec := serve(router, http.MethodGet, "/v2/secret")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("X-Old") != "" {
+		t.Errorf("without credentials: got %d headers %v", rec.Code, rec.Header())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v2/secret", nil)
+	req.SetBasicAuth("u", "p")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "/v2/secret" {
+		t.Errorf("with credentials: got %d %q", rec.Code, rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/v2/old", nil)
+	req.SetBasicAuth("u", "p")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "next not found" {
+		t.Errorf("old route: got %d %q", rec.Code, rec.Body.String())
+	}
+	if items := router.Items(); len(items) != 1 || items[0].Pattern != "/v2/secret" {
+		t.Errorf("items: got %+v", items)
+	}
+}
+
+func TestRouterReplaceConcurrent(t *testing.T) {
+	router := NewRouter()
+	router.Get("/a", echoRoute)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			next := NewRouter()
+			next.Get("/a", echoRoute)
+			router.Replace(next)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if rec := serve(router, http.MethodGet, "/a"); rec.Code != http.StatusOK {
+			t.Fatalf("got %d", rec.Code)
+		}
+	}
+	<-done
+}
+
+func TestRouterConflicts(t *testing.T) {
+	tests := []struct {
+		first    string
+		second   string
+		conflict bool
+	}{
+		{"/users/:id", "/users/:name", true},
+		{"/users/:id<int>", "/users/:name<int>", true},
+		{"/files/*path", "/files/*rest", true},
+		{"/users/:id", "/users/:id<int>", false},
+		{"/files/*path", "/files/new", false},
+		{"/files/*path", "/files/:name", false},
+		{"/users/new", "/users/:id", false},
+	}
+	for _, tt := range tests {
+		router := NewRouter()
+		if err := router.TryHandle(http.MethodGet, tt.first, echoRoute); err != nil {
+			t.Fatalf("handle %s: %v", tt.first, err)
+		}
+		err := router.TryHandle(http.MethodGet, tt.second, echoRoute)
+		if (err != nil) != tt.conflict {
+			t.Errorf("%s vs %s: got %v, want conflict %v", tt.first, tt.second, err, tt.conflict)
+			continue
+		}
+		if err != nil {
+			msg := err.Error()
+			if !strings.Contains(msg, tt.first) || !strings.Contains(msg, tt.second) || !strings.Contains(msg, "router_test.go") || !strings.Contains(msg, "static > param > wildcard") {
+				t.Errorf("%s vs %s: unexpected message %q", tt.first, tt.second, msg)
+			}
+		}
+	}
+	router := NewRouter()
+	router.Get("/files/*path", echoRoute)
+	router.Get("/files/:name", echoRoute)
+	router.Get("/files/new", echoRoute)
+	for path, route := range map[string]string{
+		"/files/new":   "/files/new",
+		"/files/a.txt": "/files/:name",
+		"/files/a/b":   "/files/*path",
+	} {
+		if rec := serve(router, http.MethodGet, path); rec.Body.String() != route {
+			t.Errorf("GET %s: got %q, want %q", path, rec.Body.String(), route)
+		}
+	}
+}
+
+func TestRouterComposeOnce(t *testing.T) {
+	wraps := 0
+	router := NewRouter().RedirectTrailingSlash(true).Use(func(h HandlerFunc) HandlerFunc {
+		wraps++
+		return h
+	})
+	router.Get("/a", echoRoute)
+	router.Get("/b/", echoRoute)
+	for _, p := range []string{"/a", "/a/", "/b", "/nope", "/a", "/b/"} {
+		serve(router, http.MethodGet, p)
+		serve(router, http.MethodPost, p)
+		serve(router, http.MethodOptions, p)
+	}
+	if wraps != 6 {
+		t.Errorf("middleware wrapped %d times, want 6", wraps)
+	}
+	if rec := serve(router, http.MethodGet, "/a/?q=1"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/a?q=1" {
+		t.Errorf("redirect: got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestRouterRequireHandler(t *testing.T) {
+	router := NewRouter()
+	if err := router.TryHandle(http.MethodGet, "/", nil); err == nil || !strings.Contains(err.Error(), "require handler") {
+		t.Errorf("nil handler: got %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("nil error handler: want panic")
+			}
+		}()
+		router.GetE("/e", nil)
+	}()
+}
+
+func TestRouterRedirectOpen(t *testing.T) {
+	router := NewRouter().RedirectTrailingSlash(true).RedirectFixedPath(true)
+	router.Get("/:slug", echoRoute)
+	router.Get("/:slug/:id", echoRoute)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for _, target := range []string{"/\\evil.com/", "//evil.com/", "/\\evil.com/x/", "/%5Cevil.com/", "/a\\b/../\\evil.com/"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.URL.Opaque = target
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if location := resp.Header.Get("Location"); location != "" {
+			t.Errorf("GET %s: got %d location %q", target, resp.StatusCode, location)
+		}
+	}
+	if rec := serve(router, http.MethodGet, "/ok/"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/ok" {
+		t.Errorf("GET /ok/: got %d location %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+type discardWriter struct{ header http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func allocRouter() *Router {
+	router := NewRouter()
+	router.Get("/static", func(w ResponseWriter, r *Request) {})
+	router.Get("/users/:id/posts/:post", func(w ResponseWriter, r *Request) {
+		r.Param("post")
+	})
+	return router
+}
+
+func TestRouterAllocs(t *testing.T) {
+	router := allocRouter()
+	w := &discardWriter{header: http.Header{}}
+	// One for the request copy from WithContext, one for the context that
+	// carries the store, route and params.
+	for _, target := range []string{"/static", "/users/1/posts/2"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if n := testing.AllocsPerRun(100, func() { router.ServeHTTP(w, req) }); n > 2 {
+			t.Errorf("GET %s: got %v allocs, want at most 2", target, n)
+		}
+	}
+}
+
+func BenchmarkRouterStatic(b *testing.B) {
+	benchmarkRouter(b, "/static")
+}
+
+func BenchmarkRouterParams(b *testing.B) {
+	benchmarkRouter(b, "/users/1/posts/2")
+}
+
+func benchmarkRouter(b *testing.B, target string) {
+	router := allocRouter()
+	w := &discardWriter{header: http.Header{}}
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
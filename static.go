@@ -0,0 +1,172 @@
+package deer
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const staticParamKey = "filepath"
+
+type StaticOptions struct {
+	Browse bool
+	Index  []string
+	SPA    bool
+}
+
+func (router *Router) Static(prefix string, dir string, options ...StaticOptions) *Router {
+	return router.StaticFS(prefix, os.DirFS(dir), options...)
+}
+
+func (router *Router) StaticFS(prefix string, fsys fs.FS, options ...StaticOptions) *Router {
+	if fsys == nil {
+		panic(newError("static", "require fs"))
+	}
+	var finalOptions StaticOptions
+	if len(options) > 0 {
+		finalOptions = options[0]
+	}
+	if len(finalOptions.Index) == 0 {
+		finalOptions.Index = []string{"index.html"}
+	}
+	s := &staticServer{router: router, fsys: fsys, options: finalOptions}
+	return router.Get(normalizePrefix(prefix)+"/*"+staticParamKey, s.serve)
+}
+
+type staticServer struct {
+	router  *Router
+	fsys    fs.FS
+	options StaticOptions
+	etags   sync.Map
+}
+
+func (s *staticServer) serve(w ResponseWriter, r *Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.Param(staticParamKey)), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		s.notFound(w, r)
+		return
+	}
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		s.fallback(w, r)
+		return
+	}
+	if info.IsDir() {
+		if !strings.HasSuffix(r.Path(), "/") {
+			target := url.URL{Path: path.Base(r.Path()) + "/", RawQuery: r.Raw.URL.RawQuery}
+			w.SetHeader("Location", target.String())
+			w.StatusCode(http.StatusMovedPermanently)
+			return
+		}
+		for _, index := range s.options.Index {
+			indexName := path.Join(name, index)
+			if indexInfo, err := fs.Stat(s.fsys, indexName); err == nil && !indexInfo.IsDir() {
+				s.serveFile(w, r, indexName, indexInfo)
+				return
+			}
+		}
+		if s.options.Browse {
+			s.serveDir(w, r, name)
+			return
+		}
+		s.fallback(w, r)
+		return
+	}
+	s.serveFile(w, r, name, info)
+}
+
+func (s *staticServer) notFound(w ResponseWriter, r *Request) {
+	if rt := s.router.loadRoutes(); rt.notFound != nil {
+		rt.notFound.handler(w, r)
+		return
+	}
+	defaultNotFoundHandleFunc(w, r)
+}
+
+func (s *staticServer) fallback(w ResponseWriter, r *Request) {
+	if s.options.SPA {
+		for _, index := range s.options.Index {
+			if info, err := fs.Stat(s.fsys, index); err == nil && !info.IsDir() {
+				s.serveFile(w, r, index, info)
+				return
+			}
+		}
+	}
+	s.notFound(w, r)
+}
+
+func (s *staticServer) serveFile(w ResponseWriter, r *Request, name string, info fs.FileInfo) {
+	file, err := s.fsys.Open(name)
+	if err != nil {
+		s.notFound(w, r)
+		return
+	}
+	defer file.Close()
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		bs, err := io.ReadAll(file)
+		if err != nil {
+			panic(err)
+		}
+		content = bytes.NewReader(bs)
+	}
+	etag, err := s.etag(name, info, content)
+	if err != nil {
+		panic(err)
+	}
+	w.SetHeader("ETag", etag)
+	http.ServeContent(w, r.Raw, info.Name(), info.ModTime(), content)
+}
+
+func (s *staticServer) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano()), nil
+	}
+	if v, ok := s.etags.Load(name); ok {
+		return v.(string), nil
+	}
+	hash := sha1.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := strconv.Quote(hex.EncodeToString(hash.Sum(nil)))
+	s.etags.Store(name, etag)
+	return etag, nil
+}
+
+func (s *staticServer) serveDir(w ResponseWriter, r *Request, name string) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		s.notFound(w, r)
+		return
+	}
+	builder := strings.Builder{}
+	builder.WriteString("<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		u := url.URL{Path: entryName}
+		builder.WriteString(fmt.Sprintf("<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(entryName)))
+	}
+	builder.WriteString("</pre>\n")
+	w.HTML(http.StatusOK, builder.String())
+}
@@ -0,0 +1,124 @@
+package deer
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func staticTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":         {Data: []byte("<h1>home</h1>")},
+		"app.js":             {Data: []byte("console.log(1)")},
+		"docs/readme.txt":    {Data: []byte("readme")},
+		"images/logo.svg":    {Data: []byte("<svg/>")},
+		"images/icons/a.svg": {Data: []byte("<svg/>")},
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	router := NewRouter().HandleNotFound(func(w ResponseWriter, r *Request) {
+		w.Text(http.StatusNotFound, "custom not found")
+	})
+	router.StaticFS("/assets", staticTestFS(), StaticOptions{Browse: true})
+	tests := []struct {
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{"/assets/app.js", http.StatusOK, "console.log(1)", ""},
+		{"/assets/", http.StatusOK, "<h1>home</h1>", ""},
+		{"/assets/docs/readme.txt", http.StatusOK, "readme", ""},
+		{"/assets/docs", http.StatusMovedPermanently, "", "docs/"},
+		{"/assets/docs/", http.StatusOK, "<pre>\n<a href=\"readme.txt\">readme.txt</a>\n</pre>\n", ""},
+		{"/assets/missing.js", http.StatusNotFound, "custom not found", ""},
+		{"/assets/../go.mod", http.StatusNotFound, "custom not found", ""},
+	}
+	for _, tt := range tests {
+		rec := serve(router, http.MethodGet, tt.path)
+		if rec.Code != tt.code || rec.Header().Get("Location") != tt.location {
+			t.Errorf("GET %s: got %d location %q, want %d location %q", tt.path, rec.Code, rec.Header().Get("Location"), tt.code, tt.location)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: got body %q, want %q", tt.path, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestStaticFSConditional(t *testing.T) {
+	router := NewRouter()
+	router.StaticFS("/", staticTestFS())
+	rec := serve(router, http.MethodGet, "/app.js")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("got %d etag %q", rec.Code, etag)
+	}
+	if rec := serve(router, http.MethodGet, "/app.js", "If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("if-none-match: got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/app.js", "Range", "bytes=0-6"); rec.Code != http.StatusPartialContent || rec.Body.String() != "console" {
+		t.Errorf("range: got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStaticFSSPA(t *testing.T) {
+	router := NewRouter()
+	router.StaticFS("/", staticTestFS(), StaticOptions{SPA: true})
+	for _, p := range []string{"/dashboard/settings", "/images/"} {
+		if rec := serve(router, http.MethodGet, p); rec.Code != http.StatusOK || rec.Body.String() != "<h1>home</h1>" {
+			t.Errorf("GET %s: got %d %q", p, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestStaticFSMounted(t *testing.T) {
+	child := NewRouter()
+	child.StaticFS("/assets", staticTestFS())
+	router := NewRouter()
+	router.Mount("/billing", child)
+	rec := serve(router, http.MethodGet, "/billing/assets/docs?v=1")
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("got %d", rec.Code)
+	}
+	base, _ := url.Parse("http://example.com/billing/assets/docs?v=1")
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base.ResolveReference(location).String(); got != "http://example.com/billing/assets/docs/?v=1" {
+		t.Errorf("redirect resolves to %q", got)
+	}
+	rec = serve(router, http.MethodGet, "/billing/assets/images/")
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "logo.svg") {
+		t.Errorf("browse disabled: got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStaticTraversal(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "public"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "public", "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter()
+	router.Static("/s", filepath.Join(root, "public"))
+	if rec := serve(router, http.MethodGet, "/s/a.txt"); rec.Code != http.StatusOK || rec.Body.String() != "a" {
+		t.Errorf("got %d %q", rec.Code, rec.Body.String())
+	}
+	for _, p := range []string{"/s/../secret.txt", "/s/..%2fsecret.txt", "/s/%2e%2e/secret.txt"} {
+		if rec := serve(router, http.MethodGet, p); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: got %d %q", p, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package deer
+
+import (
+	"fmt"
+	"strings"
+)
+
+type nodeKind uint8
+
+const (
+	staticNode nodeKind = iota
+	paramNode
+	wildcardNode
+)
+
+type node struct {
+	kind       nodeKind
+	sep        byte
+	path       string
+	indices    string
+	children   []*node
+	params     []*node
+	wildcard   *node
+	constraint *constraint
+	entry      *entry
+}
+
+type param struct {
+	key   string
+	value string
+	typed interface{}
+}
+
+func newTree(sep byte) *node {
+	return &node{kind: staticNode, sep: sep}
+}
+
+func (n *node) insert(pattern string, e *entry) {
+	if pattern == "" {
+		n.entry = e
+		return
+	}
+	switch pattern[0] {
+	case ':':
+		name, spec, rest, err := splitParam(pattern[1:], n.sep)
+		if err != nil {
+			panic(newError("router", "invalid pattern %q: %s", e.pattern, err))
+		}
+		if name == "" {
+			panic(newError("router", "empty param name in pattern %q", e.pattern))
+		}
+		var child *node
+		for _, p := range n.params {
+			if p.path == name && p.constraintSpec() == spec {
+				child = p
+				break
+			}
+		}
+		if child == nil {
+			child = &node{kind: paramNode, sep: n.sep, path: name}
+			if spec != "" {
+				c, err := parseConstraint(spec)
+				if err != nil {
+					panic(newError("router", "invalid pattern %q: %s", e.pattern, err))
+				}
+				child.constraint = c
+			}
+			n.addParam(child)
+		}
+		child.insert(rest, e)
+	case '*':
+		name := pattern[1:]
+		if name == "" {
+			panic(newError("router", "empty wildcard name in pattern %q", e.pattern))
+		}
+		if strings.IndexByte(name, n.sep) >= 0 {
+			panic(newError("router", "wildcard must be the last segment in pattern %q", e.pattern))
+		}
+		if n.wildcard == nil {
+			n.wildcard = &node{kind: wildcardNode, sep: n.sep}
+		}
+		n.wildcard.path = name
+		n.wildcard.entry = e
+	default:
+		end := strings.IndexAny(pattern, ":*")
+		if end < 0 {
+			end = len(pattern)
+		}
+		chunk := pattern[:end]
+		i := strings.IndexByte(n.indices, chunk[0])
+		if i < 0 {
+			child := &node{kind: staticNode, sep: n.sep, path: chunk}
+			n.indices += chunk[:1]
+			n.children = append(n.children, child)
+			child.insert(pattern[end:], e)
+			return
+		}
+		child := n.children[i]
+		l := commonPrefixLen(chunk, child.path)
+		if l < len(child.path) {
+			child.split(l)
+		}
+		child.insert(pattern[l:], e)
+	}
+}
+
+func (n *node) constraintSpec() string {
+	if n.constraint == nil {
+		return ""
+	}
+	return n.constraint.spec
+}
+
+func (n *node) addParam(child *node) {
+	if child.constraint == nil {
+		n.params = append(n.params, child)
+		return
+	}
+	i := 0
+	for i < len(n.params) && n.params[i].constraint != nil {
+		i++
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+}
+
+func (n *node) match(value string) (interface{}, bool) {
+	if n.constraint == nil {
+		return nil, true
+	}
+	return n.constraint.parse(value)
+}
+
+func (n *node) split(i int) {
+	tail := &node{
+		kind:     staticNode,
+		sep:      n.sep,
+		path:     n.path[i:],
+		indices:  n.indices,
+		children: n.children,
+		params:   n.params,
+		wildcard: n.wildcard,
+		entry:    n.entry,
+	}
+	*n = node{
+		kind:     staticNode,
+		sep:      n.sep,
+		path:     n.path[:i],
+		indices:  tail.path[:1],
+		children: []*node{tail},
+	}
+}
+
+func (n *node) lookup(path string, params []param) (*entry, []param) {
+	if path == "" {
+		if n.entry != nil {
+			return n.entry, params
+		}
+		if n.wildcard != nil {
+			return n.wildcard.entry, append(params, param{key: n.wildcard.path})
+		}
+		return nil, params
+	}
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.path) {
+			if e, ps := child.lookup(path[len(child.path):], params); e != nil {
+				return e, ps
+			}
+		}
+	}
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, n.sep)
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			for _, child := range n.params {
+				typed, ok := child.match(path[:end])
+				if !ok {
+					continue
+				}
+				if e, ps := child.lookup(path[end:], append(params, param{key: child.path, value: path[:end], typed: typed})); e != nil {
+					return e, ps
+				}
+			}
+		}
+	}
+	if n.wildcard != nil {
+		return n.wildcard.entry, append(params, param{key: n.wildcard.path, value: path})
+	}
+	return nil, params
+}
+
+func (n *node) lookupFold(path string, buf []byte) ([]byte, bool) {
+	if path == "" {
+		return buf, n.entry != nil || n.wildcard != nil
+	}
+	for _, child := range n.children {
+		if len(path) >= len(child.path) && strings.EqualFold(path[:len(child.path)], child.path) {
+			if result, ok := child.lookupFold(path[len(child.path):], append(buf, child.path...)); ok {
+				return result, true
+			}
+		}
+	}
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, n.sep)
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			for _, child := range n.params {
+				if _, ok := child.match(path[:end]); !ok {
+					continue
+				}
+				if result, ok := child.lookupFold(path[end:], append(buf, path[:end]...)); ok {
+					return result, true
+				}
+			}
+		}
+	}
+	if n.wildcard != nil {
+		return append(buf, path...), true
+	}
+	return buf, false
+}
+
+func patternShape(pattern string, sep byte) (string, error) {
+	builder := strings.Builder{}
+	for pattern != "" {
+		switch pattern[0] {
+		case ':':
+			name, spec, rest, err := splitParam(pattern[1:], sep)
+			if err != nil {
+				return "", err
+			}
+			if name == "" {
+				return "", fmt.Errorf("empty param name")
+			}
+			builder.WriteByte(':')
+			if spec != "" {
+				if _, err := parseConstraint(spec); err != nil {
+					return "", err
+				}
+				builder.WriteString("<" + spec + ">")
+			}
+			pattern = rest
+		case '*':
+			name := pattern[1:]
+			if name == "" {
+				return "", fmt.Errorf("empty wildcard name")
+			}
+			if strings.IndexByte(name, sep) >= 0 {
+				return "", fmt.Errorf("wildcard must be the last segment")
+			}
+			builder.WriteByte('*')
+			pattern = ""
+		default:
+			end := strings.IndexAny(pattern, ":*")
+			if end < 0 {
+				end = len(pattern)
+			}
+			builder.WriteString(pattern[:end])
+			pattern = pattern[end:]
+		}
+	}
+	return builder.String(), nil
+}
+
+func commonPrefixLen(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	i := 0
+	for i < n && a[i] == b[i] {
+		i++
+	}
+	return i
+}
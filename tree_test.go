@@ -0,0 +1,123 @@
+package deer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTreeLookup(t *testing.T) {
+	patterns := []string{
+		"/",
+		"/users",
+		"/users/new",
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/:id/posts/:post",
+		"/a/:x/c",
+		"/a/b/d",
+		"/items/:id<int>",
+		"/items/:slug",
+		"/files/new",
+		"/files/*path",
+		"/src/*filepath",
+	}
+	root := newTree('/')
+	for _, p := range patterns {
+		root.insert(p, &entry{pattern: p})
+	}
+	tests := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/", "/", nil},
+		{"/users", "/users", nil},
+		{"/users/new", "/users/new", nil},
+		{"/users/42", "/users/:id", map[string]string{"id": "42"}},
+		{"/users/newer", "/users/:id", map[string]string{"id": "newer"}},
+		{"/users/42/posts", "/users/:id/posts", map[string]string{"id": "42"}},
+		{"/users/new/posts", "/users/:id/posts", map[string]string{"id": "new"}},
+		{"/users/42/posts/7", "/users/:id/posts/:post", map[string]string{"id": "42", "post": "7"}},
+		{"/a/b/c", "/a/:x/c", map[string]string{"x": "b"}},
+		{"/a/b/d", "/a/b/d", nil},
+		{"/items/42", "/items/:id<int>", map[string]string{"id": "42"}},
+		{"/items/abc", "/items/:slug", map[string]string{"slug": "abc"}},
+		{"/files/new", "/files/new", nil},
+		{"/files/new/x", "/files/*path", map[string]string{"path": "new/x"}},
+		{"/files/a/b", "/files/*path", map[string]string{"path": "a/b"}},
+		{"/files/", "/files/*path", map[string]string{"path": ""}},
+		{"/src/", "/src/*filepath", map[string]string{"filepath": ""}},
+		{"/users/", "", nil},
+		{"/nope", "", nil},
+		{"/a/b", "", nil},
+	}
+	for _, tt := range tests {
+		e, ps := root.lookup(tt.path, nil)
+		if tt.pattern == "" {
+			if e != nil {
+				t.Errorf("lookup %q: got %q, want no match", tt.path, e.pattern)
+			}
+			continue
+		}
+		if e == nil {
+			t.Errorf("lookup %q: got no match, want %q", tt.path, tt.pattern)
+			continue
+		}
+		if e.pattern != tt.pattern {
+			t.Errorf("lookup %q: got %q, want %q", tt.path, e.pattern, tt.pattern)
+		}
+		got := map[string]string{}
+		for _, p := range ps {
+			got[p.key] = p.value
+		}
+		if tt.params == nil {
+			tt.params = map[string]string{}
+		}
+		if !reflect.DeepEqual(got, tt.params) {
+			t.Errorf("lookup %q: got params %v, want %v", tt.path, got, tt.params)
+		}
+	}
+}
+
+func TestTreeInsertInvalid(t *testing.T) {
+	tests := []string{
+		"/users/:",
+		"/files/*",
+		"/files/*path/more",
+		"/items/:id<float>",
+		"/items/:id<int",
+		"/items/:id<int>x",
+	}
+	for _, pattern := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("insert %q: want panic", pattern)
+				}
+			}()
+			newTree('/').insert(pattern, &entry{pattern: pattern})
+		}()
+	}
+}
+
+func TestPatternShape(t *testing.T) {
+	tests := []struct {
+		pattern string
+		shape   string
+	}{
+		{"/users/:id", "/users/:"},
+		{"/users/:name", "/users/:"},
+		{"/items/:id<int>", "/items/:<int>"},
+		{"/files/*path", "/files/*"},
+	}
+	for _, tt := range tests {
+		shape, err := patternShape(tt.pattern, '/')
+		if err != nil {
+			t.Errorf("shape %q: %v", tt.pattern, err)
+			continue
+		}
+		if shape != tt.shape {
+			t.Errorf("shape %q: got %q, want %q", tt.pattern, shape, tt.shape)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package deer
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/medivhyang/duck/naming"
+)
+
+const validateTagKey = "validate"
+
+type Validator interface {
+	Validate(i interface{}) error
+}
+
+type ValidationError struct {
+	Field string      `json:"field"`
+	Rule  string      `json:"rule"`
+	Param string      `json:"param,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Param != "" {
+		return fmt.Sprintf("%s: failed rule %s=%s", e.Field, e.Rule, e.Param)
+	}
+	return fmt.Sprintf("%s: failed rule %s", e.Field, e.Rule)
+}
+
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// DefaultValidator checks `validate` struct tags: required, omitempty, min,
+// max, email and oneof. Rules also run on zero values, so an int tagged
+// min=1 rejects 0, unless the field is tagged omitempty. Fields are reported
+// by their json names; the Bind methods report them by the names they were
+// bound from instead.
+var DefaultValidator Validator = &tagValidator{}
+
+type tagValidator struct {
+	cache sync.Map
+}
+
+// fieldNaming selects how validation errors name a field, matching the
+// source it was bound from.
+type fieldNaming int
+
+const (
+	jsonNaming fieldNaming = iota
+	xmlNaming
+	bindingNaming
+)
+
+type fieldsKey struct {
+	t     reflect.Type
+	names fieldNaming
+}
+
+type validateRule struct {
+	name  string
+	param string
+	check func(v reflect.Value) bool
+}
+
+type validateField struct {
+	index     int
+	name      string
+	required  bool
+	omitempty bool
+	rules     []validateRule
+}
+
+func (tv *tagValidator) Validate(i interface{}) error {
+	return tv.validateNamed(i, jsonNaming)
+}
+
+func (tv *tagValidator) validateNamed(i interface{}, names fieldNaming) error {
+	var errs ValidationErrors
+	tv.validate(reflect.ValueOf(i), "", names, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (tv *tagValidator) validate(v reflect.Value, path string, names fieldNaming, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range tv.fields(v.Type(), names) {
+			fv := v.Field(f.index)
+			fieldPath := f.name
+			if path != "" {
+				fieldPath = path + "." + f.name
+			}
+			if isEmptyValue(fv) {
+				if f.required {
+					*errs = append(*errs, ValidationError{Field: fieldPath, Rule: "required", Value: fv.Interface()})
+					continue
+				}
+				if f.omitempty {
+					continue
+				}
+			}
+			for _, rule := range f.rules {
+				if !rule.check(fv) {
+					*errs = append(*errs, ValidationError{Field: fieldPath, Rule: rule.name, Param: rule.param, Value: fv.Interface()})
+				}
+			}
+			tv.validate(fv, fieldPath, names, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			tv.validate(v.Index(i), fmt.Sprintf("%s[%d]", path, i), names, errs)
+		}
+	}
+}
+
+func (tv *tagValidator) fields(t reflect.Type, names fieldNaming) []validateField {
+	key := fieldsKey{t: t, names: names}
+	if cached, ok := tv.cache.Load(key); ok {
+		return cached.([]validateField)
+	}
+	var fields []validateField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get(validateTagKey)
+		if tag == "-" {
+			continue
+		}
+		f := validateField{index: i, name: fieldName(sf, names)}
+		for _, spec := range strings.Split(tag, ",") {
+			spec = strings.TrimSpace(spec)
+			switch spec {
+			case "":
+				continue
+			case "required":
+				f.required = true
+				continue
+			case "omitempty":
+				f.omitempty = true
+				continue
+			}
+			rule, err := parseValidateRule(spec, sf.Type)
+			if err != nil {
+				panic(newError("validate", "field %s.%s: %s", t.Name(), sf.Name, err))
+			}
+			f.rules = append(f.rules, rule)
+		}
+		fields = append(fields, f)
+	}
+	tv.cache.Store(key, fields)
+	return fields
+}
+
+// fieldName reports a field the way clients send it: by its json or xml
+// name, or for query and form binding by its binding tag or the snake_case
+// of its Go name.
+func fieldName(sf reflect.StructField, names fieldNaming) string {
+	switch names {
+	case bindingNaming:
+		if name, ok := sf.Tag.Lookup(bindingTagKey); ok {
+			return name
+		}
+		return naming.ToSnake(sf.Name)
+	case xmlNaming:
+		if name, _, _ := strings.Cut(sf.Tag.Get("xml"), ","); name != "" && name != "-" {
+			return name
+		}
+	default:
+		if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func parseValidateRule(spec string, t reflect.Type) (validateRule, error) {
+	name, param := spec, ""
+	if i := strings.IndexByte(spec, '='); i >= 0 {
+		name, param = spec[:i], spec[i+1:]
+	}
+	rule := validateRule{name: name, param: param}
+	switch name {
+	case "min", "max":
+		if !measurable(t) {
+			return rule, fmt.Errorf("%s does not apply to %s", name, t)
+		}
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return rule, fmt.Errorf("invalid %s param %q", name, param)
+		}
+		rule.check = func(v reflect.Value) bool {
+			n, ok := measure(v)
+			if !ok {
+				return false
+			}
+			if name == "min" {
+				return n >= limit
+			}
+			return n <= limit
+		}
+	case "email":
+		rule.check = func(v reflect.Value) bool {
+			s, ok := stringValue(v)
+			if !ok {
+				return false
+			}
+			addr, err := mail.ParseAddress(s)
+			return err == nil && addr.Address == s
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		if len(options) == 0 {
+			return rule, fmt.Errorf("oneof requires options")
+		}
+		rule.check = func(v reflect.Value) bool {
+			for v.Kind() == reflect.Ptr {
+				v = v.Elem()
+			}
+			if !v.IsValid() {
+				return false
+			}
+			return containsString(options, fmt.Sprint(v.Interface()))
+		}
+	default:
+		return rule, fmt.Errorf("unknown rule %q", name)
+	}
+	return rule, nil
+}
+
+func measure(v reflect.Value) (float64, bool) {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	default:
+		return 0, false
+	}
+}
+
+func measurable(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+func stringValue(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String {
+		return "", false
+	}
+	return v.String(), true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,194 @@
+package deer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	type address struct {
+		City string `json:"city" validate:"required"`
+	}
+	type order struct {
+		Qty      int       `json:"qty" validate:"min=1"`
+		Note     string    `json:"note" validate:"omitempty,max=3"`
+		Email    string    `binding:"contact" validate:"omitempty,email"`
+		Status   string    `json:"status,omitempty" validate:"oneof=new paid"`
+		Coupon   *string   `json:"coupon" validate:"omitempty,oneof=A B"`
+		Priority *int      `json:"priority" validate:"oneof=1 2"`
+		Name     string    `json:"-" validate:"required"`
+		Address  address   `json:"address"`
+		Items    []address `json:"items"`
+	}
+	valid := "A"
+	one := 1
+	tests := []struct {
+		name  string
+		value order
+		want  []string
+	}{
+		{
+			"zero value",
+			order{},
+			[]string{"qty:min", "status:oneof", "priority:oneof", "Name:required", "address.city:required"},
+		},
+		{
+			"valid",
+			order{Qty: 2, Status: "paid", Coupon: &valid, Priority: &one, Name: "x", Address: address{City: "y"}},
+			nil,
+		},
+		{
+			"invalid values",
+			order{Qty: 1, Note: "long", Email: "nope", Status: "paid", Priority: &one, Name: "x", Address: address{City: "y"}, Items: []address{{City: "z"}, {}}},
+			[]string{"note:max", "Email:email", "items[1].city:required"},
+		},
+	}
+	for _, tt := range tests {
+		err := DefaultValidator.Validate(&tt.value)
+		var got []string
+		var errs ValidationErrors
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				got = append(got, e.Field+":"+e.Rule)
+			}
+		} else if err != nil {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBindValidates(t *testing.T) {
+	type query struct {
+		Qty  int    `binding:"qty" validate:"min=1"`
+		Sort string `binding:"sort" validate:"omitempty,oneof=asc desc"`
+	}
+	router := NewRouter()
+	router.GetE("/", func(w ResponseWriter, r *Request) error {
+		var q query
+		if err := r.BindQuery(&q); err != nil {
+			return err
+		}
+		w.Text(http.StatusOK, "ok")
+		return nil
+	})
+	router.PostE("/", func(w ResponseWriter, r *Request) error {
+		var q struct {
+			Qty int `json:"qty" validate:"min=1"`
+		}
+		if err := r.BindJSON(&q); err != nil {
+			return err
+		}
+		w.Text(http.StatusOK, "ok")
+		return nil
+	})
+	tests := []struct {
+		method string
+		target string
+		body   string
+		code   int
+		field  string
+	}{
+		{http.MethodGet, "/?qty=2", "", http.StatusOK, ""},
+		{http.MethodGet, "/?qty=2&sort=desc", "", http.StatusOK, ""},
+		{http.MethodGet, "/", "", http.StatusUnprocessableEntity, `"field":"qty"`},
+		{http.MethodGet, "/?qty=0", "", http.StatusUnprocessableEntity, `"field":"qty"`},
+		{http.MethodGet, "/?qty=1&sort=up", "", http.StatusUnprocessableEntity, `"field":"sort"`},
+		{http.MethodPost, "/", `{"qty":0}`, http.StatusUnprocessableEntity, `"field":"qty"`},
+		{http.MethodPost, "/", `{}`, http.StatusUnprocessableEntity, `"field":"qty"`},
+		{http.MethodPost, "/", `{"qty":3}`, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), tt.field) {
+			t.Errorf("%s %s %q: got %d %q", tt.method, tt.target, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestBindValidationNames(t *testing.T) {
+	type params struct {
+		UserName string `validate:"required"`
+		Page     int    `binding:"p" json:"page" validate:"min=1"`
+		Sort     string `json:"sort_by" xml:"order" validate:"required"`
+	}
+	router := NewRouter()
+	router.PostE("/:source", func(w ResponseWriter, r *Request) error {
+		var p params
+		switch r.Param("source") {
+		case "query":
+			return r.BindQuery(&p)
+		case "form":
+			return r.BindForm(&p)
+		case "json":
+			return r.BindJSON(&p)
+		default:
+			return r.BindXML(&p)
+		}
+	})
+	tests := []struct {
+		target string
+		body   string
+		want   string
+	}{
+		{"/query?p=0", "", `"field":"user_name"`},
+		{"/query?p=0", "", `"field":"p"`},
+		{"/query?p=0", "", `"field":"sort"`},
+		{"/form", "p=0", `"field":"user_name"`},
+		{"/form", "p=0", `"field":"p"`},
+		{"/json", `{"page":0}`, `"field":"UserName"`},
+		{"/json", `{"page":0}`, `"field":"page"`},
+		{"/json", `{"page":0}`, `"field":"sort_by"`},
+		{"/xml", `<params><Page>0</Page></params>`, `"field":"Page"`},
+		{"/xml", `<params><Page>0</Page></params>`, `"field":"order"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s %q: got %d %q, want %s", tt.target, tt.body, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestValidatorInvalidTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"unknown rule", struct {
+			Qty int `validate:"between=1"`
+		}{}},
+		{"min on bool", struct {
+			Active bool `validate:"min=1"`
+		}{}},
+		{"max on struct", struct {
+			Address struct{ City string } `validate:"max=1"`
+		}{}},
+		{"min on bool pointer", struct {
+			Active *bool `validate:"min=1"`
+		}{}},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: want panic", tt.name)
+				}
+			}()
+			DefaultValidator.Validate(tt.value)
+		}()
+	}
+}